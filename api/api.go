@@ -3,13 +3,11 @@ package api
 import (
 	"encoding/json"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -22,9 +20,18 @@ type Api struct {
 	fileObjectCache string
 	fileCommand     string
 	fileStatus      string
-	statusData      *StatusData
-	staticData      *StaticData
-	mutex           sync.RWMutex
+	nrdpTokens      []string
+	dryRunMode      bool
+	auditLog        *auditLog
+	cgiConfig       *cgiConfig
+	roles           *roleConfig
+	tls             *tlsFiles
+	refreshMin      time.Duration
+	refreshMax      time.Duration
+	confirmations   *commandTracker
+	// current holds the *snapshot served, mutex serializes publishing a new one
+	current atomic.Value
+	mutex   sync.Mutex
 }
 
 func stringInSlice(a string, list []string) bool {
@@ -37,13 +44,16 @@ func stringInSlice(a string, list []string) bool {
 }
 
 // NewAPI create new api object
-func NewAPI(addr, fileObjectCache, fileCommand, fileStatus string) *Api {
+func NewAPI(addr, fileObjectCache, fileCommand, fileStatus string, nrdpTokens []string, dryRun bool) *Api {
 	api := &Api{
 		addr:            addr,
 		router:          mux.NewRouter(),
 		fileObjectCache: fileObjectCache,
 		fileCommand:     fileCommand,
 		fileStatus:      fileStatus,
+		nrdpTokens:      nrdpTokens,
+		dryRunMode:      dryRun,
+		confirmations:   newCommandTracker(),
 	}
 
 	api.buildRoutes()
@@ -54,23 +64,27 @@ func NewAPI(addr, fileObjectCache, fileCommand, fileStatus string) *Api {
 func (s *Api) Run() error {
 	log.Println("Reading object cache from ", s.fileObjectCache)
 	log.Println("Writing commands to ", s.fileCommand)
-
-	oc, err := os.Open(s.fileObjectCache)
-	if err != nil {
-		return err
+	if s.dryRunMode {
+		log.Println("Dry run mode: commands are validated but never written")
 	}
-	defer oc.Close()
 
-	s.staticData, err = readObjectCache(oc)
-	if err != nil {
+	static := newFileWatch(s.fileObjectCache, s.refreshMin, s.refreshMax, s.refreshStaticData)
+	if err := static.check(); err != nil {
 		return fmt.Errorf("Unable to parse object cache file: %s", err)
 	}
-	go s.spawnRefreshRoutein()
-	go s.spawnRefreshStaticRoutine()
+	go static.run(nil)
+	go newFileWatch(s.fileStatus, s.refreshMin, s.refreshMax, s.refreshStatusData).run(nil)
 
 	http.Handle("/", s.router)
 
-	err = http.ListenAndServe(s.addr, nil)
+	var err error
+	if s.tls != nil {
+		log.Println("Serving HTTPS with ", s.tls.certFile)
+		server := &http.Server{Addr: s.addr, TLSConfig: s.tls.config()}
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = http.ListenAndServe(s.addr, nil)
+	}
 	if err != nil {
 		return err
 	}
@@ -78,32 +92,25 @@ func (s *Api) Run() error {
 	return nil
 }
 
-func (s *Api) spawnRefreshRoutein() {
-	for {
-		data, err := s.refreshStatusDataFile()
-		if err != nil {
-			log.Println("Unable to refresh status data: ", err)
-		} else {
-			s.mutex.Lock()
-			s.statusData = data
-			s.mutex.Unlock()
-		}
-		time.Sleep(60 * time.Second)
+// refreshStatusData replaces the status data with the contents of status.dat
+func (s *Api) refreshStatusData(modTime time.Time) error {
+	data, err := s.refreshStatusDataFile()
+	if err != nil {
+		return err
 	}
+	s.publishStatus(data, modTime)
+	s.confirmations.observe(data)
+	return nil
 }
 
-func (s *Api) spawnRefreshStaticRoutine() {
-	for {
-		data, err := s.refreshStaticDataFile()
-		if err != nil {
-			log.Println("Unable to parse object cache file: ", err)
-		} else {
-			s.mutex.Lock()
-			s.staticData = data
-			s.mutex.Unlock()
-		}
-		time.Sleep(5 * time.Minute)
+// refreshStaticData replaces the static data with the contents of objects.cache
+func (s *Api) refreshStaticData(modTime time.Time) error {
+	data, err := s.refreshStaticDataFile()
+	if err != nil {
+		return err
 	}
+	s.publishStatic(data, modTime)
+	return nil
 }
 
 func (s *Api) refreshStaticDataFile() (*StaticData, error) {
@@ -118,225 +125,259 @@ func (s *Api) refreshStaticDataFile() (*StaticData, error) {
 	return readObjectCache(fh)
 }
 
-func readObjectCache(in io.Reader) (*StaticData, error) {
-	data := NewStaticData()
-	dat, err := ioutil.ReadAll(in)
-	if err != nil {
-		return nil, err
+type StatusData struct {
+	Contacts         []*ContactStatus
+	Services         []*ServiceStatus
+	Hosts            []*HostStatus
+	HostServices     map[string][]*ServiceStatus
+	HostComments     []*HostComment
+	ServiceComments  []*ServiceComment
+	HostDowntimes    []*HostDowntime
+	ServiceDowntimes []*ServiceDowntime
+	Info             *InfoStatus
+	Program          *ProgramStatus
+}
+
+func NewStatusData() *StatusData {
+	return &StatusData{
+		HostServices: make(map[string][]*ServiceStatus),
 	}
+}
 
-	a := strings.SplitAfterN(string(dat), "}", -1)
-	for _, i := range a {
-		lines := strings.Split(i, "\n")
-		if stringInSlice("define command {", lines) {
-			// We dont do anything with defined commands for now
-		}
+type StaticData struct {
+	Commands            []*Command
+	Timeperiods         []*Timeperiod
+	Contacts            []*Contact
+	Contactgroups       []*Contactgroup
+	Hosts               []*Host
+	Hostgroups          []*Hostgroup
+	Services            []*Service
+	Servicegroups       []*Servicegroup
+	HostDependencies    []*HostDependency
+	ServiceDependencies []*ServiceDependency
+	HostEscalations     []*HostEscalation
+	ServiceEscalations  []*ServiceEscalation
+
+	// Lookups by name, built by index once the definitions were read. Authorization checks
+	// every host and service served, so scanning the definitions would be quadratic.
+	hosts            map[string]*Host
+	services         map[hostService]*Service
+	hostgroups       map[string]*Hostgroup
+	hostgroupSets    map[string]map[string]bool
+	servicegroups    map[string]*Servicegroup
+	contacts         map[string]*Contact
+	contactgroupSets map[string]map[string]bool
+}
 
-		if stringInSlice("define contactgroup {", lines) {
-			// We dont do anything with contactgroup for now
-		}
+// hostService identifies a service by its host
+type hostService struct {
+	host    string
+	service string
+}
 
-		if stringInSlice("define hostgroup {", lines) {
-			thisgroup := map[string]string{}
-			for _, i := range lines {
-				if i == "define hostgroup {" || strings.TrimSpace(i) == "}" || i == "" {
-					// Ignore these lines
-				} else {
-					if len(strings.Fields(i)) != 0 {
-						thisgroup[strings.TrimSpace(strings.Fields(i)[0])] = strings.Join(strings.Fields(i)[1:], " ")
-					}
-				}
-			}
-			data.hostgroupList = append(data.hostgroupList, thisgroup)
-		}
+func NewStaticData() *StaticData {
+	d := &StaticData{}
+	d.index()
+	return d
+}
 
-		if stringInSlice("define contact {", lines) {
-			thiscontact := map[string]string{}
-			for _, i := range lines {
-				if i == "define contact {" || strings.TrimSpace(i) == "}" || i == "" {
-					// Ignore these lines
-				} else {
-					if len(strings.Fields(i)) != 0 {
-						thiscontact[strings.TrimSpace(strings.Fields(i)[0])] = strings.Join(strings.Fields(i)[1:], " ")
-					}
-				}
-			}
-			data.contactList = append(data.contactList, thiscontact)
+// index builds the lookups by name. The first definition of a name wins, as Nagios refuses
+// duplicates anyway.
+func (d *StaticData) index() {
+	d.hosts = make(map[string]*Host, len(d.Hosts))
+	for _, item := range d.Hosts {
+		if _, ok := d.hosts[item.HostName]; !ok {
+			d.hosts[item.HostName] = item
 		}
-
-		if stringInSlice("define host {", lines) {
-			thishost := map[string]string{}
-			for _, i := range lines {
-				if i == "define host {" || strings.TrimSpace(i) == "}" || i == "" {
-					// Ignore these lines
-				} else {
-					if len(strings.Fields(i)) != 0 {
-						thishost[strings.TrimSpace(strings.Fields(i)[0])] = strings.Join(strings.Fields(i)[1:], " ")
-					}
-				}
-			}
-			data.hostList = append(data.hostList, thishost)
+	}
+	d.services = make(map[hostService]*Service, len(d.Services))
+	for _, item := range d.Services {
+		key := hostService{item.HostName, item.ServiceDescription}
+		if _, ok := d.services[key]; !ok {
+			d.services[key] = item
 		}
-
-		if stringInSlice("define service {", lines) {
-			thisservice := map[string]string{}
-			for _, i := range lines {
-				if i == "define service {" || strings.TrimSpace(i) == "}" || i == "" {
-					// Ignore these lines
-				} else {
-					if len(strings.Fields(i)) != 0 {
-						thisservice[strings.TrimSpace(strings.Fields(i)[0])] = strings.Join(strings.Fields(i)[1:], " ")
-					}
-				}
-			}
-			data.serviceList = append(data.serviceList, thisservice)
+	}
+	d.hostgroups = make(map[string]*Hostgroup, len(d.Hostgroups))
+	d.hostgroupSets = make(map[string]map[string]bool, len(d.Hostgroups))
+	for _, item := range d.Hostgroups {
+		if _, ok := d.hostgroups[item.HostgroupName]; !ok {
+			d.hostgroups[item.HostgroupName] = item
+			d.hostgroupSets[item.HostgroupName] = stringSet(item.Members)
 		}
-
 	}
+	d.servicegroups = make(map[string]*Servicegroup, len(d.Servicegroups))
+	for _, item := range d.Servicegroups {
+		if _, ok := d.servicegroups[item.ServicegroupName]; !ok {
+			d.servicegroups[item.ServicegroupName] = item
+		}
+	}
+	d.contacts = make(map[string]*Contact, len(d.Contacts))
+	for _, item := range d.Contacts {
+		if _, ok := d.contacts[item.ContactName]; !ok {
+			d.contacts[item.ContactName] = item
+		}
+	}
+	d.contactgroupSets = make(map[string]map[string]bool, len(d.Contactgroups))
+	for _, item := range d.Contactgroups {
+		if _, ok := d.contactgroupSets[item.ContactgroupName]; !ok {
+			d.contactgroupSets[item.ContactgroupName] = stringSet(item.Members)
+		}
+	}
+}
 
-	return data, nil
+func stringSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
 }
 
-type StatusData struct {
-	Contacts     []*ContactStatus
-	Services     []*ServiceStatus
-	Hosts        []*HostStatus
-	HostServices map[string][]*ServiceStatus
+// hasHost reports whether a host with the given name is configured
+func (d *StaticData) hasHost(host string) bool {
+	return d.host(host) != nil
 }
 
-func NewStatusData() *StatusData {
-	return &StatusData{
-		HostServices: make(map[string][]*ServiceStatus),
-	}
+// hostgroup returns the given hostgroup, or nil when it is not configured
+func (d *StaticData) hostgroup(group string) *Hostgroup {
+	return d.hostgroups[group]
 }
 
-type StaticData struct {
-	contactList   []map[string]string
-	serviceList   []map[string]string
-	hostList      []map[string]string
-	hostgroupList []map[string]string
+// hasHostgroup reports whether a hostgroup with the given name is configured
+func (d *StaticData) hasHostgroup(group string) bool {
+	return d.hostgroup(group) != nil
 }
 
-func NewStaticData() *StaticData {
-	return &StaticData{}
+// inHostgroup reports whether host is a member of the given hostgroup
+func (d *StaticData) inHostgroup(group, host string) bool {
+	return d.hostgroupSets[group][host]
 }
 
-type settableType interface {
-	setField(key, value string) error
-	setCustomVariable(key, value string)
+// hostgroupMembers returns the names of the hosts in the given hostgroup
+func (d *StaticData) hostgroupMembers(group string) []string {
+	if item := d.hostgroup(group); item != nil {
+		return item.Members
+	}
+	return nil
 }
 
-func parseBlock(o settableType, objecttype string, lines []string) error {
-	start := objecttype + " {"
-	for _, i := range lines {
-		if i == start || i == "    }" || i == "" || strings.TrimSpace(strings.Split(i, " ")[0]) == "}" {
-			// Ignore these lines
-		} else {
-			pieces := strings.SplitN(strings.TrimSpace(i), "=", 2)
-			if strings.HasPrefix(pieces[0], "_") {
-				o.setCustomVariable(strings.TrimPrefix(pieces[0], "_"), strings.SplitN(pieces[1], ";", 2)[1])
-			} else {
-				o.setField(pieces[0], pieces[1])
-			}
+// hostgroupServiceMembers returns the names of the hosts in the given hostgroup the given
+// service is configured on
+func (d *StaticData) hostgroupServiceMembers(group, service string) []string {
+	var hosts []string
+	for _, host := range d.hostgroupMembers(group) {
+		if d.hasService(host, service) {
+			hosts = append(hosts, host)
 		}
 	}
+	return hosts
+}
 
-	return nil
+// servicegroup returns the given servicegroup, or nil when it is not configured
+func (d *StaticData) servicegroup(group string) *Servicegroup {
+	return d.servicegroups[group]
 }
 
-func (s *Api) refreshStatusDataFile() (*StatusData, error) {
-	log.Println("Refreshing data from ", s.fileStatus)
+// hasServicegroup reports whether a servicegroup with the given name is configured
+func (d *StaticData) hasServicegroup(group string) bool {
+	return d.servicegroup(group) != nil
+}
 
-	fh, err := os.Open(s.fileStatus)
-	if err != nil {
-		return nil, err
+// servicegroupMembers returns the members of the given servicegroup as host name, service description pairs
+func (d *StaticData) servicegroupMembers(group string) []string {
+	if item := d.servicegroup(group); item != nil {
+		return item.Members
 	}
-	defer fh.Close()
+	return nil
+}
 
-	return refreshStatusData(fh)
+// hasContact reports whether a contact with the given name is configured
+func (d *StaticData) hasContact(contact string) bool {
+	return d.contacts[contact] != nil
 }
 
-func refreshStatusData(fh io.Reader) (*StatusData, error) {
-	data := NewStatusData()
-	dat, err := ioutil.ReadAll(fh)
-	if err != nil {
-		return nil, err
-	}
+// hasContactgroup reports whether a contactgroup with the given name is configured
+func (d *StaticData) hasContactgroup(group string) bool {
+	_, ok := d.contactgroupSets[group]
+	return ok
+}
 
-	a := strings.SplitAfterN(string(dat), "}", -1)
-	for _, i := range a {
-		lines := strings.Split(i, "\n")
-		if stringInSlice("contactstatus {", lines) {
-			obj := &ContactStatus{}
-			parseBlock(obj, "contactstatus", lines)
-			data.Contacts = append(data.Contacts, obj)
-		}
+// inContactgroup reports whether contact is a member of the given contactgroup
+func (d *StaticData) inContactgroup(group, contact string) bool {
+	return d.contactgroupSets[group][contact]
+}
 
-		if stringInSlice("servicestatus {", lines) {
-			obj := &ServiceStatus{}
-			parseBlock(obj, "servicestatus", lines)
-			data.Services = append(data.Services, obj)
+// host returns the definition of the given host, or nil when it is not configured
+func (d *StaticData) host(host string) *Host {
+	return d.hosts[host]
+}
 
-			data.HostServices[obj.HostName] = append(data.HostServices[obj.HostName], obj)
-		}
+// service returns the definition of the given service on the given host, or nil when it is not configured
+func (d *StaticData) service(host, service string) *Service {
+	return d.services[hostService{host, service}]
+}
 
-		if stringInSlice("hoststatus {", lines) {
-			obj := &HostStatus{}
-			parseBlock(obj, "hoststatus", lines)
-			data.Hosts = append(data.Hosts, obj)
-		}
+// hasService reports whether the given service is configured on the given host
+func (d *StaticData) hasService(host, service string) bool {
+	return d.service(host, service) != nil
+}
+
+type settableType interface {
+	setField(key, value string) error
+	setCustomVariable(key, value string)
+}
+
+func (s *Api) refreshStatusDataFile() (*StatusData, error) {
+	log.Println("Refreshing data from ", s.fileStatus)
+
+	fh, err := os.Open(s.fileStatus)
+	if err != nil {
+		return nil, err
 	}
+	defer fh.Close()
 
-	return data, nil
+	return refreshStatusData(fh)
 }
 
 // HandleGetContacts returns all configured contactlist
 // GET: /contacts
 func (a *Api) HandleGetContacts(w http.ResponseWriter, r *http.Request) {
+	snap := a.snapshotOf(r)
+
+	z := a.authorization(r)
+	var contacts []map[string]string
+	for _, item := range snap.static.Contacts {
+		if z.canSeeContact(item.ContactName) {
+			contacts = append(contacts, item.Attributes)
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(a.staticData.contactList)
+	json.NewEncoder(w).Encode(contacts)
 }
 
 // HandleGetAllHostStatus returns hoststatus for all hosts
 // GET: /hoststatus
 func (a *Api) HandleGetAllHostStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	a.mutex.RLock()
-	defer a.mutex.RUnlock()
-	json.NewEncoder(w).Encode(a.statusData.Hosts)
+	json.NewEncoder(w).Encode(a.visibleHostStatus(r))
 }
 
 // HandleGetHostStatusForHost returns hoststatus for requested host only
 // GET: /hoststatus/<host>
 func (a *Api) HandleGetHostStatusForHost(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	host, ok := vars["hostname"]
-	if !ok {
-		http.Error(w, "Could not find host to lookup", 400)
+	item := a.requestedHostStatus(w, r)
+	if item == nil {
 		return
 	}
-
-	a.mutex.RLock()
-	defer a.mutex.RUnlock()
-	for _, item := range a.statusData.Hosts {
-		if item.HostName == host {
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(item)
-			return
-		}
-	}
-
-	http.Error(w, "Host not found", 404)
-	return
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
 }
 
 // HandleGetServiceStatus return all servicestatus
 // GET: /servicestatus
 func (a *Api) HandleGetServiceStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	a.mutex.RLock()
-	defer a.mutex.RUnlock()
-	json.NewEncoder(w).Encode(a.statusData.Services)
+	json.NewEncoder(w).Encode(a.visibleServiceStatus(r, ""))
 }
 
 // HandleGetServiceStatusForService returns all servicestatus for requested service only
@@ -349,19 +390,61 @@ func (a *Api) HandleGetServiceStatusForService(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	var serviceList []*ServiceStatus
-	a.mutex.RLock()
-	defer a.mutex.RUnlock()
-	for _, item := range a.statusData.Services {
-		if item.ServiceDescription == service {
-			serviceList = append(serviceList, item)
-		}
-	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(serviceList)
+	json.NewEncoder(w).Encode(a.visibleServiceStatus(r, service))
 	return
 }
 
+// visibleHostStatus returns the hoststatus of every host the caller of r may see.
+func (a *Api) visibleHostStatus(r *http.Request) []*HostStatus {
+	snap := a.snapshotOf(r)
+	z := a.authorization(r)
+	var hosts []*HostStatus
+	for _, item := range snap.status.Hosts {
+		if z.canSeeHost(item.HostName) {
+			hosts = append(hosts, item)
+		}
+	}
+	return hosts
+}
+
+// requestedHostStatus returns the hoststatus of the host in the request path, or replies
+// with an error and returns nil.
+func (a *Api) requestedHostStatus(w http.ResponseWriter, r *http.Request) *HostStatus {
+	snap := a.snapshotOf(r)
+	vars := mux.Vars(r)
+	host, ok := vars["hostname"]
+	if !ok {
+		http.Error(w, "Could not find host to lookup", 400)
+		return nil
+	}
+
+	item := findHostStatus(snap.status, host)
+	if item == nil {
+		http.Error(w, "Host not found", 404)
+		return nil
+	}
+	if !a.authorization(r).canSeeHost(host) {
+		http.Error(w, fmt.Sprintf("Error: not authorized for host %s", host), http.StatusForbidden)
+		return nil
+	}
+	return item
+}
+
+// visibleServiceStatus returns the servicestatus the caller of r may see, of every service or
+// only of those described as service.
+func (a *Api) visibleServiceStatus(r *http.Request, service string) []*ServiceStatus {
+	snap := a.snapshotOf(r)
+	z := a.authorization(r)
+	var services []*ServiceStatus
+	for _, item := range snap.status.Services {
+		if (service == "" || item.ServiceDescription == service) && z.canSeeService(item.HostName, item.ServiceDescription) {
+			services = append(services, item)
+		}
+	}
+	return services
+}
+
 // HandleGetHost retruns host info only on the host requested
 // GET: /host/<hostname>
 func (a *Api) HandleGetHost(w http.ResponseWriter, r *http.Request) {
@@ -373,48 +456,74 @@ func (a *Api) HandleGetHost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	for _, item := range a.staticData.hostList {
-		if item["host_name"] == host {
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(item)
-			return
-		}
-	}
+	snap := a.snapshotOf(r)
 
-	http.Error(w, "Host Not Found", 404)
-	return
+	item := snap.static.host(host)
+	if item == nil {
+		http.Error(w, "Host Not Found", 404)
+		return
+	}
+	if !a.authorization(r).canSeeHost(host) {
+		http.Error(w, fmt.Sprintf("Error: not authorized for host %s", host), http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item.Attributes)
 }
 
 // HandleGetServicesForHost retruns all services defined for the given host
 // GET: /host/<hostname>/services
 func (a *Api) HandleGetServicesForHost(w http.ResponseWriter, r *http.Request) {
+	services, ok := a.requestedHostServices(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services)
+}
+
+// requestedHostServices returns the servicestatus of the host in the request path the caller
+// may see, or replies with an error and returns false.
+func (a *Api) requestedHostServices(w http.ResponseWriter, r *http.Request) ([]*ServiceStatus, bool) {
+	snap := a.snapshotOf(r)
 	vars := mux.Vars(r)
 	host, ok := vars["hostname"]
 	if !ok {
 		http.Error(w, "Invalid hostname provided", 400)
-		return
+		return nil, false
 	}
 
-	a.mutex.RLock()
-	defer a.mutex.RUnlock()
-
-	sList, ok := a.statusData.HostServices[host]
+	sList, ok := snap.status.HostServices[host]
 	if !ok {
 		http.Error(w, "Host Not Found", 404)
-		return
+		return nil, false
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(sList)
+	// Contacts of a single service see that service without the rest of the host
+	z := a.authorization(r)
+	services := []*ServiceStatus{}
+	for _, item := range sList {
+		if z.canSeeService(item.HostName, item.ServiceDescription) {
+			services = append(services, item)
+		}
+	}
+	if len(services) == 0 && !z.canSeeHost(host) {
+		http.Error(w, fmt.Sprintf("Error: not authorized for host %s", host), http.StatusForbidden)
+		return nil, false
+	}
+	return services, true
 }
 
 // HandleGetConfiguredHosts returns a list with configured host names
 // GET: /hosts
 func (a *Api) HandleGetConfiguredHosts(w http.ResponseWriter, r *http.Request) {
+	snap := a.snapshotOf(r)
+
+	z := a.authorization(r)
 	var thesehosts []string
-	for _, item := range a.staticData.hostList {
-		h := item["host_name"]
-		if !stringInSlice(h, thesehosts) {
+	for _, item := range snap.static.Hosts {
+		h := item.HostName
+		if z.canSeeHost(h) && !stringInSlice(h, thesehosts) {
 			thesehosts = append(thesehosts, h)
 		}
 	}
@@ -426,10 +535,10 @@ func (a *Api) HandleGetConfiguredHosts(w http.ResponseWriter, r *http.Request) {
 // GET: /services
 func (a *Api) HandleGetConfiguredServices(w http.ResponseWriter, r *http.Request) {
 	var services []string
-	a.mutex.RLock()
-	defer a.mutex.RUnlock()
-	for _, item := range a.statusData.Services {
-		if !stringInSlice(item.ServiceDescription, services) {
+	snap := a.snapshotOf(r)
+	z := a.authorization(r)
+	for _, item := range snap.status.Services {
+		if z.canSeeService(item.HostName, item.ServiceDescription) && !stringInSlice(item.ServiceDescription, services) {
 			services = append(services, item.ServiceDescription)
 		}
 	}
@@ -446,15 +555,156 @@ type hostGroup struct {
 // HandleGetHostGroups returns all defined hostgroups
 // GET: /hostgroups
 func (a *Api) HandleGetHostGroups(w http.ResponseWriter, r *http.Request) {
+	snap := a.snapshotOf(r)
+
+	// Only members the caller is authorized for are listed, and groups without any are left out
+	z := a.authorization(r)
 	var hg []hostGroup
-	for _, item := range a.staticData.hostgroupList {
-		group := hostGroup{HostGroupName: item["hostgroup_name"], Alias: item["alias"], Members: strings.Split(item["members"], ",")}
-		hg = append(hg, group)
+	for _, item := range snap.static.Hostgroups {
+		group := hostGroup{HostGroupName: item.HostgroupName, Alias: item.Alias}
+		for _, member := range item.Members {
+			if z.canSeeHost(member) {
+				group.Members = append(group.Members, member)
+			}
+		}
+		if len(group.Members) > 0 {
+			hg = append(hg, group)
+		}
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(hg)
 }
 
+type programInfo struct {
+	Info          *InfoStatus    `json:"info"`
+	ProgramStatus *ProgramStatus `json:"programstatus"`
+}
+
+// HandleGetProgram returns nagios program status and info
+// GET: /program
+func (a *Api) HandleGetProgram(w http.ResponseWriter, r *http.Request) {
+	snap := a.snapshotOf(r)
+
+	if !a.authorization(r).has(rightSystemInformation) {
+		http.Error(w, "Error: not authorized for system information", http.StatusForbidden)
+		return
+	}
+
+	program := programInfo{
+		Info:          snap.status.Info,
+		ProgramStatus: snap.status.Program,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(program)
+}
+
+type commentList struct {
+	HostComments    []*HostComment    `json:"host_comments"`
+	ServiceComments []*ServiceComment `json:"service_comments"`
+}
+
+// HandleGetComments returns all host and service comments
+// GET: /comments
+func (a *Api) HandleGetComments(w http.ResponseWriter, r *http.Request) {
+	snap := a.snapshotOf(r)
+
+	z := a.authorization(r)
+	var comments commentList
+	for _, item := range snap.status.HostComments {
+		if z.canSeeHost(item.HostName) {
+			comments.HostComments = append(comments.HostComments, item)
+		}
+	}
+	for _, item := range snap.status.ServiceComments {
+		if z.canSeeService(item.HostName, item.ServiceDescription) {
+			comments.ServiceComments = append(comments.ServiceComments, item)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comments)
+}
+
+// HandleGetCommentsForHost returns host comments and comments on services of the given host
+// GET: /host/<hostname>/comments
+func (a *Api) HandleGetCommentsForHost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	host, ok := vars["hostname"]
+	if !ok {
+		http.Error(w, "Invalid hostname provided", 400)
+		return
+	}
+
+	snap := a.snapshotOf(r)
+
+	z := a.authorization(r)
+	var comments commentList
+	for _, item := range snap.status.HostComments {
+		if item.HostName == host && z.canSeeHost(host) {
+			comments.HostComments = append(comments.HostComments, item)
+		}
+	}
+	for _, item := range snap.status.ServiceComments {
+		if item.HostName == host && z.canSeeService(host, item.ServiceDescription) {
+			comments.ServiceComments = append(comments.ServiceComments, item)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comments)
+}
+
+// HandleGetCommentsForService returns comments for requested service only
+// GET: /servicestatus/<service>/comments
+func (a *Api) HandleGetCommentsForService(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	service, ok := vars["service"]
+	if !ok {
+		http.Error(w, "Could not find service to lookup", 400)
+		return
+	}
+
+	var comments []*ServiceComment
+	snap := a.snapshotOf(r)
+	z := a.authorization(r)
+	for _, item := range snap.status.ServiceComments {
+		if item.ServiceDescription == service && z.canSeeService(item.HostName, service) {
+			comments = append(comments, item)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comments)
+}
+
+type downtimeList struct {
+	HostDowntimes    []*HostDowntime    `json:"host_downtimes"`
+	ServiceDowntimes []*ServiceDowntime `json:"service_downtimes"`
+}
+
+// HandleGetDowntimes returns scheduled downtimes, optionally filtered by host and service
+// GET: /downtimes?host=<hostname>&service=<service>
+func (a *Api) HandleGetDowntimes(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Query().Get("host")
+	service := r.URL.Query().Get("service")
+
+	snap := a.snapshotOf(r)
+
+	z := a.authorization(r)
+	var downtimes downtimeList
+	if service == "" {
+		for _, item := range snap.status.HostDowntimes {
+			if (host == "" || item.HostName == host) && z.canSeeHost(item.HostName) {
+				downtimes.HostDowntimes = append(downtimes.HostDowntimes, item)
+			}
+		}
+	}
+	for _, item := range snap.status.ServiceDowntimes {
+		if (host == "" || item.HostName == host) && (service == "" || item.ServiceDescription == service) && z.canSeeService(item.HostName, item.ServiceDescription) {
+			downtimes.ServiceDowntimes = append(downtimes.ServiceDowntimes, item)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(downtimes)
+}
+
 // HandleForcedHostServiceChecks executes SCHEDULE_FORCED_HOST_SVC_CHECKS
 // GET: /host/hostname/force
 func (a *Api) HandleForcedHostServiceChecks(w http.ResponseWriter, r *http.Request) {
@@ -465,9 +715,9 @@ func (a *Api) HandleForcedHostServiceChecks(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	a.mutex.RLock()
-	defer a.mutex.RUnlock()
+	if !a.requireHost(w, r, host) {
+		return
+	}
 
-	command := fmt.Sprintf("%s;%s;[%d]", "SCHEDULE_FORCED_HOST_SVC_CHECKS", host, time.Now().Unix())
-	a.WriteCommandToFile(w, command)
+	a.executeCommand(w, r, "SCHEDULE_FORCED_HOST_SVC_CHECKS", host, time.Now().Unix())
 }
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cheekybits/is"
+)
+
+func TestRefreshStatusData(t *testing.T) {
+	is := is.New(t)
+
+	fh, err := os.Open("testdata/status.dat")
+	is.NoErr(err)
+	defer fh.Close()
+
+	data, err := refreshStatusData(fh)
+	is.NoErr(err)
+
+	is.NotNil(data.Info)
+	is.Equal(data.Info.Version, "4.2.4")
+	is.Equal(data.Info.Created, "1484082900")
+
+	is.NotNil(data.Program)
+	is.Equal(data.Program.NagiosPid, "1234")
+	is.Equal(data.Program.ProgramStart, "1484000000")
+	is.Equal(data.Program.EnableNotifications, "1")
+
+	is.Equal(len(data.Hosts), 1)
+	is.Equal(data.Hosts[0].HostName, "web01")
+	is.Equal(len(data.Services), 1)
+	is.Equal(len(data.HostServices["web01"]), 1)
+
+	is.Equal(len(data.HostComments), 1)
+	is.Equal(data.HostComments[0].HostName, "web01")
+	is.Equal(data.HostComments[0].CommentID, "4")
+	is.Equal(data.HostComments[0].CommentData, "Scheduled for replacement")
+
+	is.Equal(len(data.ServiceComments), 1)
+	is.Equal(data.ServiceComments[0].ServiceDescription, "HTTP")
+	is.Equal(data.ServiceComments[0].CommentID, "7")
+	is.Equal(data.ServiceComments[0].Author, "jason")
+
+	is.Equal(len(data.HostDowntimes), 1)
+	is.Equal(data.HostDowntimes[0].DowntimeID, "1")
+	is.Equal(data.HostDowntimes[0].Fixed, "1")
+	is.Equal(data.HostDowntimes[0].IsInEffect, "1")
+
+	is.Equal(len(data.ServiceDowntimes), 1)
+	is.Equal(data.ServiceDowntimes[0].ServiceDescription, "HTTP")
+	is.Equal(data.ServiceDowntimes[0].TriggeredBy, "1")
+	is.Equal(data.ServiceDowntimes[0].Comment, "Deploy")
+}
@@ -0,0 +1,347 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Sebor/nagios-api/auth"
+)
+
+// Results recorded for a command besides write errors
+const (
+	auditWritten = "written"
+	auditDryRun  = "dry_run"
+)
+
+type auditRecord struct {
+	Time      time.Time `json:"time"`
+	Principal string    `json:"principal"`
+	Roles     []string  `json:"roles,omitempty"`
+	ClientIP  string    `json:"client_ip"`
+	Endpoint  string    `json:"endpoint"`
+	Command   string    `json:"command"`
+	Result    string    `json:"result"`
+}
+
+// auditLog appends records to a JSON-lines file, rotating it to <path>.1 ... <path>.<backups>
+// once it grows past maxSize bytes
+type auditLog struct {
+	mutex   sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	file    *os.File
+	size    int64
+}
+
+func newAuditLog(path string, maxSize int64, backups int) (*auditLog, error) {
+	l := &auditLog{path: path, maxSize: maxSize, backups: backups}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *auditLog) open() error {
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// rotate shifts the backups up by one, dropping the oldest, and starts a new file
+func (l *auditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	for i := l.backups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+	}
+	var err error
+	if l.backups > 0 {
+		err = os.Rename(l.path, l.path+".1")
+	} else {
+		err = os.Remove(l.path)
+	}
+	if err != nil {
+		// Keep appending to the current file rather than losing records
+		l.open()
+		return err
+	}
+	return l.open()
+}
+
+func (l *auditLog) write(records []auditRecord) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var lines []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(lines)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(lines)
+	l.size += int64(n)
+	return err
+}
+
+// Bounds of an audit log query
+const (
+	defaultAuditLimit = 1000
+	maxAuditLimit     = 10000
+	// maxAuditLine is the longest record read back, longer lines are skipped
+	maxAuditLine = 1024 * 1024
+)
+
+// auditFile is a file of the audit log opened for reading up to size bytes
+type auditFile struct {
+	file *os.File
+	size int64
+}
+
+// files opens the backups, oldest first, and the current file. Only opening them needs the lock:
+// the records written so far stay readable through the open files when the log is rotated.
+func (l *auditLog) files() ([]auditFile, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var files []auditFile
+	for i := l.backups; i >= 0; i-- {
+		path := l.path
+		if i > 0 {
+			path = fmt.Sprintf("%s.%d", l.path, i)
+		}
+
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			closeAuditFiles(files)
+			return nil, err
+		}
+
+		size := l.size
+		if i > 0 {
+			info, err := f.Stat()
+			if err != nil {
+				f.Close()
+				closeAuditFiles(files)
+				return nil, err
+			}
+			size = info.Size()
+		}
+		files = append(files, auditFile{file: f, size: size})
+	}
+	return files, nil
+}
+
+func closeAuditFiles(files []auditFile) {
+	for _, f := range files {
+		f.file.Close()
+	}
+}
+
+// readAuditLines calls fn with every line of r, skipping lines longer than maxAuditLine
+func readAuditLines(r io.Reader, fn func(line []byte)) error {
+	reader := bufio.NewReader(r)
+	var line []byte
+	tooLong := false
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+			tooLong = len(line) > maxAuditLine
+		}
+		if isPrefix {
+			continue
+		}
+		if !tooLong {
+			fn(line)
+		}
+		line, tooLong = line[:0], false
+	}
+}
+
+// query returns the last limit records, oldest first, written between from and to (when not
+// zero) that match. Writes are not blocked while the files are read.
+func (l *auditLog) query(from, to time.Time, match func(record *auditRecord) bool, limit int) ([]auditRecord, error) {
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+	defer closeAuditFiles(files)
+
+	records := []auditRecord{}
+	for _, f := range files {
+		err := readAuditLines(io.LimitReader(f.file, f.size), func(line []byte) {
+			var record auditRecord
+			if err := json.Unmarshal(line, &record); err != nil {
+				return
+			}
+			if (!from.IsZero() && record.Time.Before(from)) || (!to.IsZero() && record.Time.Truncate(time.Second).After(to)) {
+				return
+			}
+			if match != nil && !match(&record) {
+				return
+			}
+			if len(records) == limit {
+				records = records[1:]
+			}
+			records = append(records, record)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// EnableAuditLog records every command written to nagios command file, or validated in dry run,
+// to a JSON-lines file at path. The file is rotated once it is larger than maxSize bytes, keeping
+// backups older files.
+func (a *Api) EnableAuditLog(path string, maxSize int64, backups int) error {
+	l, err := newAuditLog(path, maxSize, backups)
+	if err != nil {
+		return err
+	}
+	a.auditLog = l
+	return nil
+}
+
+// audit records the outcome of commands issued by a request
+func (a *Api) audit(r *http.Request, commands []string, result string) {
+	if a.auditLog == nil {
+		return
+	}
+
+	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		clientIP = r.RemoteAddr
+	}
+
+	now := time.Now()
+	records := make([]auditRecord, len(commands))
+	for i, command := range commands {
+		records[i] = auditRecord{
+			Time:      now,
+			Principal: auth.Principal(r),
+			Roles:     auth.Roles(r),
+			ClientIP:  clientIP,
+			Endpoint:  r.Method + " " + r.URL.Path,
+			Command:   command,
+			Result:    result,
+		}
+	}
+
+	if err := a.auditLog.write(records); err != nil {
+		log.Println("Unable to write audit log: ", err)
+	}
+}
+
+// submitCommands writes commands to nagios command file in one append, or only returns true in
+// dry run, and records the outcome in the audit log. Nothing is written when the caller is not
+// authorized for one of the commands.
+func (a *Api) submitCommands(r *http.Request, commands []string) (bool, error) {
+	if err := a.authorizeCommands(r, commands); err != nil {
+		a.audit(r, commands, fmt.Sprintf("error: %s", err))
+		return false, err
+	}
+
+	if a.dryRun(r) {
+		a.audit(r, commands, auditDryRun)
+		return true, nil
+	}
+
+	if err := a.WriteCommands(commands); err != nil {
+		a.audit(r, commands, fmt.Sprintf("error: %s", err))
+		return false, err
+	}
+	a.audit(r, commands, auditWritten)
+	return false, nil
+}
+
+// parseAuditTime accepts a unix timestamp or an RFC 3339 time
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// HandleGetAudit returns the last limit (1000 by default) audit records of commands issued
+// through the API. Callers without the system information right only get their own records.
+// GET: /audit[?from=<time>&to=<time>&principal=<name>&limit=<count>]
+func (a *Api) HandleGetAudit(w http.ResponseWriter, r *http.Request) {
+	if a.auditLog == nil {
+		http.Error(w, "Error: audit log is not enabled", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := parseAuditTime(query.Get("from"))
+	if err != nil {
+		http.Error(w, "Error: from must be a unix timestamp or an RFC 3339 time", http.StatusBadRequest)
+		return
+	}
+	to, err := parseAuditTime(query.Get("to"))
+	if err != nil {
+		http.Error(w, "Error: to must be a unix timestamp or an RFC 3339 time", http.StatusBadRequest)
+		return
+	}
+	limit := defaultAuditLimit
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > maxAuditLimit {
+			http.Error(w, fmt.Sprintf("Error: limit must be between 1 and %d", maxAuditLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ownRecords := !a.authorization(r).has(rightSystemInformation)
+	caller, principal := auth.Principal(r), query.Get("principal")
+	match := func(record *auditRecord) bool {
+		return (principal == "" || record.Principal == principal) && (!ownRecords || record.Principal == caller)
+	}
+
+	records, err := a.auditLog.query(from, to, match, limit)
+	if err != nil {
+		http.Error(w, "Could not read audit log", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
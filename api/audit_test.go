@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+)
+
+func TestAuditLogRotation(t *testing.T) {
+	is := is.New(t)
+	path := filepath.Join(filepath.Dir(newTestApi(t).fileCommand), "audit.log")
+
+	l, err := newAuditLog(path, 300, 2)
+	is.NoErr(err)
+	for i := 0; i < 10; i++ {
+		is.NoErr(l.write([]auditRecord{{Time: time.Now(), Command: fmt.Sprintf("COMMAND_%d", i), Result: auditWritten}}))
+	}
+
+	for _, suffix := range []string{"", ".1", ".2"} {
+		info, err := os.Stat(path + suffix)
+		is.NoErr(err)
+		is.True(info.Size() <= 300)
+	}
+	_, err = os.Stat(path + ".3")
+	is.True(os.IsNotExist(err))
+
+	records, err := l.query(time.Time{}, time.Time{}, nil, defaultAuditLimit)
+	is.NoErr(err)
+	is.True(len(records) < 10)
+	is.Equal(records[len(records)-1].Command, "COMMAND_9")
+	for i := 1; i < len(records); i++ {
+		is.True(records[i-1].Command < records[i].Command)
+	}
+}
+
+func TestAuditLogQuery(t *testing.T) {
+	is := is.New(t)
+	path := filepath.Join(filepath.Dir(newTestApi(t).fileCommand), "audit.log")
+
+	l, err := newAuditLog(path, 0, 0)
+	is.NoErr(err)
+	write := func(command string) {
+		is.NoErr(l.write([]auditRecord{{Time: time.Now(), Principal: "jason", Command: command, Result: auditWritten}}))
+	}
+	write("COMMAND_1")
+	write("PROCESS_SERVICE_CHECK_RESULT;web01;HTTP;0;" + strings.Repeat("x", maxAuditLine))
+	write("COMMAND_2")
+	write("COMMAND_3")
+
+	// The over-long record is skipped instead of failing the query
+	records, err := l.query(time.Time{}, time.Time{}, nil, defaultAuditLimit)
+	is.NoErr(err)
+	is.Equal(len(records), 3)
+
+	records, err = l.query(time.Time{}, time.Time{}, nil, 2)
+	is.NoErr(err)
+	is.Equal(len(records), 2)
+	is.Equal(records[0].Command, "COMMAND_2")
+	is.Equal(records[1].Command, "COMMAND_3")
+
+	records, err = l.query(time.Time{}, time.Time{}, func(record *auditRecord) bool { return record.Command == "COMMAND_1" }, 2)
+	is.NoErr(err)
+	is.Equal(len(records), 1)
+}
+
+func TestHandleGetAudit(t *testing.T) {
+	api := newTestApi(t)
+	must := is.New(t)
+
+	w := httptest.NewRecorder()
+	api.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/audit", nil))
+	must.Equal(w.Code, 404)
+
+	must.NoErr(api.EnableAuditLog(filepath.Join(filepath.Dir(api.fileCommand), "audit.log"), 0, 0))
+	for _, path := range []string{"/disable_hostgroup_host_notifications", "/disable_hostgroup_host_notifications?dry_run=true"} {
+		w = httptest.NewRecorder()
+		api.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"hostgroup": "web-servers"}`)))
+		must.Equal(w.Code, 200)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		code  int
+		count int
+	}{
+		{name: "all", query: "", code: 200, count: 2},
+		{name: "time range", query: "?from=" + strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10) + "&to=" + time.Now().Add(time.Minute).Format(time.RFC3339), code: 200, count: 2},
+		{name: "after", query: "?from=" + strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10), code: 200, count: 0},
+		{name: "principal", query: "?principal=jason", code: 200, count: 0},
+		{name: "limit", query: "?limit=1", code: 200, count: 1},
+		{name: "invalid time", query: "?to=yesterday", code: 400},
+		{name: "invalid limit", query: "?limit=0", code: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			w := httptest.NewRecorder()
+			api.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/audit"+tt.query, nil))
+			is.Equal(w.Code, tt.code)
+			if tt.code != 200 {
+				return
+			}
+
+			var records []auditRecord
+			is.NoErr(json.NewDecoder(w.Body).Decode(&records))
+			is.Equal(len(records), tt.count)
+			if tt.count == 2 {
+				is.Equal(records[0].Command, "DISABLE_HOSTGROUP_HOST_NOTIFICATIONS;web-servers")
+				is.Equal(records[0].Endpoint, "POST /disable_hostgroup_host_notifications")
+				is.Equal(records[0].ClientIP, "192.0.2.1")
+				is.Equal(records[0].Result, auditWritten)
+				is.Equal(records[1].Result, auditDryRun)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Sebor/nagios-api/auth"
+)
+
+// Rights granted in cgi.cfg, see https://assets.nagios.com/downloads/nagioscore/docs/nagioscore/4/en/cgiauth.html
+const (
+	rightSystemInformation        = "authorized_for_system_information"
+	rightConfigurationInformation = "authorized_for_configuration_information"
+	rightSystemCommands           = "authorized_for_system_commands"
+	rightAllHosts                 = "authorized_for_all_hosts"
+	rightAllServices              = "authorized_for_all_services"
+	rightAllHostCommands          = "authorized_for_all_host_commands"
+	rightAllServiceCommands       = "authorized_for_all_service_commands"
+	rightReadOnly                 = "authorized_for_read_only"
+)
+
+// cgiConfig holds the authorization settings of a Nagios cgi.cfg
+type cgiConfig struct {
+	useAuthentication bool
+	defaultUser       string
+	// rights maps each authorized_for_* setting to the users it lists, "*" meaning everyone
+	rights map[string][]string
+}
+
+func loadCgiConfig(path string) (*cgiConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseCgiConfig(f)
+}
+
+func parseCgiConfig(in io.Reader) (*cgiConfig, error) {
+	c := &cgiConfig{useAuthentication: true, rights: make(map[string][]string)}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pieces := strings.SplitN(line, "=", 2)
+		if len(pieces) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(pieces[0]), strings.TrimSpace(pieces[1])
+
+		switch {
+		case key == "use_authentication":
+			c.useAuthentication = value != "0"
+		case key == "default_user_name":
+			c.defaultUser = value
+		case strings.HasPrefix(key, "authorized_for_"):
+			c.rights[key] = splitList(value)
+		}
+	}
+	return c, scanner.Err()
+}
+
+// EnableAuthorization restricts what callers see and which commands they may issue following the
+// cgi.cfg at path, like the Nagios CGIs do: the authenticated principal is taken as the name of a
+// Nagios contact and anonymous callers as the default_user_name.
+func (a *Api) EnableAuthorization(path string) error {
+	c, err := loadCgiConfig(path)
+	if err != nil {
+		return err
+	}
+	a.cgiConfig = c
+	return nil
+}
+
+// forbiddenError is returned when the caller is not authorized for a command
+type forbiddenError struct {
+	msg string
+}
+
+func (e forbiddenError) Error() string {
+	return e.msg
+}
+
+func forbidden(format string, args ...interface{}) error {
+	return forbiddenError{msg: fmt.Sprintf(format, args...)}
+}
+
+// authorization answers what the caller of a request may see and do, from the static and status
+// data of the snapshot the request is served from
+type authorization struct {
+	// cgi is nil when everything is allowed
+	cgi    *cgiConfig
+	user   string
+	static *StaticData
+	status *StatusData
+}
+
+func (a *Api) authorization(r *http.Request) authorization {
+	snap := a.snapshotOf(r)
+	z := authorization{cgi: a.cgiConfig, user: auth.Principal(r), static: snap.static, status: snap.status}
+	if z.cgi != nil && !z.cgi.useAuthentication {
+		z.cgi = nil
+	}
+	if z.cgi != nil && z.user == "" {
+		z.user = z.cgi.defaultUser
+	}
+	return z
+}
+
+// listed reports whether the user is granted right in cgi.cfg
+func (z authorization) listed(right string) bool {
+	if z.user == "" {
+		return false
+	}
+	for _, user := range z.cgi.rights[right] {
+		if user == "*" || user == z.user {
+			return true
+		}
+	}
+	return false
+}
+
+// caller names the user in error messages
+func (z authorization) caller() string {
+	if z.user == "" {
+		return "anonymous"
+	}
+	return z.user
+}
+
+func (z authorization) has(right string) bool {
+	return z.cgi == nil || z.listed(right)
+}
+
+func (z authorization) readOnly() bool {
+	return z.cgi != nil && z.listed(rightReadOnly)
+}
+
+// isContact reports whether the user is listed in contacts or is a member of one of
+// contactGroups, the contacts and contact_groups of a host or service definition
+func (z authorization) isContact(contacts, contactGroups []string) bool {
+	if z.user == "" {
+		return false
+	}
+	if stringInSlice(z.user, contacts) {
+		return true
+	}
+	for _, group := range contactGroups {
+		if z.static.inContactgroup(group, z.user) {
+			return true
+		}
+	}
+	return false
+}
+
+func (z authorization) isHostContact(host string) bool {
+	h := z.static.host(host)
+	return h != nil && z.isContact(h.Contacts, h.ContactGroups)
+}
+
+func (z authorization) isServiceContact(host, service string) bool {
+	s := z.static.service(host, service)
+	return (s != nil && z.isContact(s.Contacts, s.ContactGroups)) || z.isHostContact(host)
+}
+
+func (z authorization) canSeeHost(host string) bool {
+	return z.has(rightAllHosts) || z.isHostContact(host)
+}
+
+func (z authorization) canSeeService(host, service string) bool {
+	return z.has(rightAllServices) || z.has(rightAllHosts) || z.isServiceContact(host, service)
+}
+
+func (z authorization) canSeeContact(contact string) bool {
+	return z.has(rightConfigurationInformation) || contact == z.user
+}
+
+func (z authorization) canCommandHost(host string) bool {
+	return !z.readOnly() && (z.has(rightAllHostCommands) || z.isHostContact(host))
+}
+
+func (z authorization) canCommandService(host, service string) bool {
+	return !z.readOnly() && (z.has(rightAllServiceCommands) || z.isServiceContact(host, service))
+}
+
+// command returns a forbiddenError when the user may not issue command, a line as rendered by
+// buildCommand. Commands on hosts and services need the matching all_*_commands right or being
+// their contact, group commands need it for every member and the rest need system_commands.
+// Hostgroup commands naming only a service need it for that service on every member.
+func (z authorization) command(command string) error {
+	if z.cgi == nil {
+		return nil
+	}
+	if z.readOnly() {
+		return forbidden("%s is only authorized for read-only access", z.caller())
+	}
+
+	fields := strings.Split(command, ";")
+	spec, ok := commandCatalogue[fields[0]]
+	if !ok {
+		if !z.has(rightSystemCommands) {
+			return forbidden("%s is not authorized for system commands", z.caller())
+		}
+		return nil
+	}
+
+	// Targets always come before the free-text argument, so splitting on every semicolon is safe
+	targets := make(map[string]string)
+	for i, p := range spec.Params {
+		if p.Target != "" && i+1 < len(fields) && fields[i+1] != "" {
+			targets[p.Target] = fields[i+1]
+		}
+	}
+
+	host, service := targets[targetHost], targets[targetService]
+	group, inHostgroup := targets[targetHostgroup]
+	// A service without a host on a hostgroup command means the service on every member
+	groupService := inHostgroup && host == "" && service != ""
+	switch {
+	case groupService:
+		for _, member := range z.static.hostgroupServiceMembers(group, service) {
+			if !z.canCommandService(member, service) {
+				return forbidden("%s is not authorized for commands on service %s on host %s of hostgroup %s", z.caller(), service, member, group)
+			}
+		}
+	case service != "":
+		if !z.canCommandService(host, service) {
+			return forbidden("%s is not authorized for commands on service %s on host %s", z.caller(), service, host)
+		}
+	case host != "":
+		if !z.canCommandHost(host) {
+			return forbidden("%s is not authorized for commands on host %s", z.caller(), host)
+		}
+	}
+
+	if inHostgroup && !groupService {
+		for _, member := range z.static.hostgroupMembers(group) {
+			if !z.canCommandHost(member) {
+				return forbidden("%s is not authorized for commands on host %s of hostgroup %s", z.caller(), member, group)
+			}
+		}
+	}
+	if group, ok := targets[targetServicegroup]; ok {
+		members := z.static.servicegroupMembers(group)
+		for i := 0; i+1 < len(members); i += 2 {
+			if !z.canCommandService(members[i], members[i+1]) {
+				return forbidden("%s is not authorized for commands on service %s on host %s of servicegroup %s", z.caller(), members[i+1], members[i], group)
+			}
+		}
+	}
+	if contact, ok := targets[targetContact]; ok && contact != z.user && !z.has(rightSystemCommands) {
+		return forbidden("%s is not authorized for commands on contact %s", z.caller(), contact)
+	}
+	if group, ok := targets[targetContactgroup]; ok && !z.has(rightSystemCommands) {
+		return forbidden("%s is not authorized for commands on contactgroup %s", z.caller(), group)
+	}
+
+	if len(targets) == 0 {
+		return z.untargetedCommand(spec, fields[1:])
+	}
+	return nil
+}
+
+// untargetedCommand authorizes commands without a host, service, group or contact argument.
+// Comments and downtimes deleted by ID are checked against the host or service they belong to.
+func (z authorization) untargetedCommand(spec commandSpec, args []string) error {
+	var id string
+	if len(args) > 0 {
+		id = args[0]
+	}
+
+	var host, service string
+	found := false
+	switch spec.Name {
+	case "DEL_HOST_COMMENT":
+		for _, c := range z.statusData().HostComments {
+			if c.CommentID == id {
+				host, found = c.HostName, true
+			}
+		}
+	case "DEL_SVC_COMMENT":
+		for _, c := range z.statusData().ServiceComments {
+			if c.CommentID == id {
+				host, service, found = c.HostName, c.ServiceDescription, true
+			}
+		}
+	case "DEL_HOST_DOWNTIME":
+		for _, d := range z.statusData().HostDowntimes {
+			if d.DowntimeID == id {
+				host, found = d.HostName, true
+			}
+		}
+	case "DEL_SVC_DOWNTIME":
+		for _, d := range z.statusData().ServiceDowntimes {
+			if d.DowntimeID == id {
+				host, service, found = d.HostName, d.ServiceDescription, true
+			}
+		}
+	default:
+		if !z.has(rightSystemCommands) {
+			return forbidden("%s is not authorized for system commands", z.caller())
+		}
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(spec.Name, "DEL_HOST_") && (found && z.canCommandHost(host) || z.has(rightAllHostCommands)):
+		return nil
+	case strings.HasPrefix(spec.Name, "DEL_SVC_") && (found && z.canCommandService(host, service) || z.has(rightAllServiceCommands)):
+		return nil
+	}
+	return forbidden("%s is not authorized for %s %s", z.caller(), spec.Name, id)
+}
+
+// statusData returns the status data read last, or an empty one before the first refresh
+func (z authorization) statusData() *StatusData {
+	if z.status == nil {
+		return NewStatusData()
+	}
+	return z.status
+}
+
+// authorizeCommands returns a forbiddenError for the first of commands the caller of r may not
+// issue, by its roles or by cgi.cfg
+func (a *Api) authorizeCommands(r *http.Request, commands []string) error {
+	if err := a.commandRoles(r, commands); err != nil {
+		return err
+	}
+
+	z := a.authorization(r)
+	for _, command := range commands {
+		if err := z.command(command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replyCommandError replies 403 when the caller was not authorized for a command and 500 when
+// it could not be written
+func replyCommandError(w http.ResponseWriter, err error) {
+	if _, ok := err.(forbiddenError); ok {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusForbidden)
+		return
+	}
+	http.Error(w, "Could not execute command", http.StatusInternalServerError)
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sebor/nagios-api/auth"
+	"github.com/cheekybits/is"
+)
+
+// newAuthorizedTestApi returns a test Api with status data from testdata/status.dat,
+// authorizing callers with testdata/cgi.cfg
+func newAuthorizedTestApi(t *testing.T) *Api {
+	api := newTestApi(t)
+
+	fh, err := os.Open("testdata/status.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	data, err := refreshStatusData(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.publishStatus(data, time.Time{})
+
+	if err := api.EnableAuthorization("testdata/cgi.cfg"); err != nil {
+		t.Fatal(err)
+	}
+	return api
+}
+
+func TestLoadCgiConfig(t *testing.T) {
+	is := is.New(t)
+
+	c, err := loadCgiConfig("testdata/cgi.cfg")
+	is.NoErr(err)
+	is.True(c.useAuthentication)
+	is.Equal(c.defaultUser, "guest")
+	is.Equal(c.rights[rightAllHosts], []string{"nagiosadmin", "guest"})
+	is.Equal(c.rights[rightReadOnly], []string{"guest"})
+
+	_, err = loadCgiConfig("testdata/missing.cfg")
+	is.Err(err)
+}
+
+func TestAuthorizeCommand(t *testing.T) {
+	api := newAuthorizedTestApi(t)
+
+	tests := []struct {
+		user    string
+		command string
+		allowed bool
+	}{
+		{user: "nagiosadmin", command: "DISABLE_NOTIFICATIONS", allowed: true},
+		{user: "alice", command: "DISABLE_NOTIFICATIONS", allowed: false},
+		{user: "alice", command: "ACKNOWLEDGE_SVC_PROBLEM;web01;HTTP;2;1;1;alice;On it", allowed: true},
+		{user: "alice", command: "DISABLE_HOST_CHECK;web01", allowed: false},
+		{user: "alice", command: "DISABLE_HOST_CHECK;db01", allowed: true},
+		{user: "alice", command: "DISABLE_SVC_CHECK;db01;MySQL", allowed: true},
+		{user: "jason", command: "DISABLE_SVC_CHECK;db01;MySQL", allowed: false},
+		{user: "alice", command: "DISABLE_HOSTGROUP_HOST_CHECKS;db-servers", allowed: true},
+		{user: "alice", command: "DISABLE_HOSTGROUP_HOST_CHECKS;web-servers", allowed: false},
+		{user: "alice", command: "DISABLE_SERVICEGROUP_SVC_CHECKS;web-checks", allowed: true},
+		{user: "alice", command: "DEL_DOWNTIME_BY_HOSTGROUP_NAME;db-servers;;MySQL", allowed: true},
+		{user: "jason", command: "DEL_DOWNTIME_BY_HOSTGROUP_NAME;db-servers;;MySQL", allowed: false},
+		{user: "alice", command: "CHANGE_CONTACT_MODATTR;alice;0", allowed: true},
+		{user: "alice", command: "CHANGE_CONTACT_MODATTR;jason;0", allowed: false},
+		{user: "alice", command: "DEL_SVC_COMMENT;7", allowed: true},
+		{user: "alice", command: "DEL_HOST_COMMENT;4", allowed: false},
+		{user: "alice", command: "DEL_HOST_COMMENT;99", allowed: false},
+		{user: "nagiosadmin", command: "DEL_HOST_COMMENT;99", allowed: true},
+		{user: "", command: "ADD_HOST_COMMENT;web01;1;guest;Read only", allowed: false},
+		{user: "nagiosadmin", command: "PROCESS_FILE;/tmp/commands;0", allowed: true},
+		{user: "alice", command: "PROCESS_FILE;/tmp/commands;0", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.user+" "+tt.command, func(t *testing.T) {
+			is := is.New(t)
+
+			r := auth.WithPrincipal(httptest.NewRequest(http.MethodPost, "/", nil), tt.user)
+			err := api.authorizeCommands(r, []string{tt.command})
+			if tt.allowed {
+				is.NoErr(err)
+				return
+			}
+			_, ok := err.(forbiddenError)
+			is.True(ok)
+		})
+	}
+}
+
+func TestAuthorizedReads(t *testing.T) {
+	api := newAuthorizedTestApi(t)
+
+	tests := []struct {
+		name string
+		user string
+		path string
+		code int
+		body string
+	}{
+		{name: "own contact", user: "alice", path: "/contacts", code: 200, body: `[{"alias":"Alice","contact_name":"alice","email":"alice@example.com"}]`},
+		{name: "hosts as contact", user: "alice", path: "/hosts", code: 200, body: `["db01"]`},
+		{name: "hosts as default user", path: "/hosts", code: 200, body: `["web01","db01"]`},
+		{name: "hoststatus as service contact", user: "alice", path: "/hoststatus", code: 200, body: `null`},
+		{name: "single hoststatus", user: "alice", path: "/hoststatus/web01", code: 403},
+		{name: "single host", user: "jason", path: "/host/db01", code: 403},
+		{name: "services of host", user: "alice", path: "/host/web01/services", code: 200},
+		{name: "services of host as other contact", user: "jason", path: "/host/web01/services", code: 403},
+		{name: "hostgroups", user: "alice", path: "/hostgroups", code: 200, body: `[{"hostgroup_name":"db-servers","alias":"Database Servers","members":["db01"]}]`},
+		{name: "program", user: "alice", path: "/program", code: 403},
+		{name: "program as admin", user: "nagiosadmin", path: "/program", code: 200},
+		{name: "comments", user: "jason", path: "/comments", code: 200, body: `{"host_comments":null,"service_comments":null}`},
+		{name: "downtimes", user: "jason", path: "/downtimes", code: 200, body: `{"host_downtimes":null,"service_downtimes":null}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			w := httptest.NewRecorder()
+			api.router.ServeHTTP(w, auth.WithPrincipal(httptest.NewRequest(http.MethodGet, tt.path, nil), tt.user))
+			is.Equal(w.Code, tt.code)
+			if tt.body != "" {
+				is.Equal(strings.TrimSpace(w.Body.String()), tt.body)
+			}
+		})
+	}
+}
+
+func TestAuthorizedServiceComments(t *testing.T) {
+	is := is.New(t)
+	api := newAuthorizedTestApi(t)
+
+	w := httptest.NewRecorder()
+	api.router.ServeHTTP(w, auth.WithPrincipal(httptest.NewRequest(http.MethodGet, "/comments", nil), "alice"))
+	is.Equal(w.Code, 200)
+
+	var comments commentList
+	is.NoErr(json.NewDecoder(w.Body).Decode(&comments))
+	is.Equal(len(comments.HostComments), 0)
+	is.Equal(len(comments.ServiceComments), 1)
+	is.Equal(comments.ServiceComments[0].CommentID, "7")
+}
+
+func TestAuthorizedCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		path     string
+		body     string
+		code     int
+		commands []string
+		// denied are the commands recorded as rejected in the audit log
+		denied []string
+	}{
+		{name: "system command", user: "alice", path: "/disable_notifications", code: 403, denied: []string{"DISABLE_NOTIFICATIONS"}},
+		{name: "read only", path: "/disable_host_check", body: `{"hostname": "db01"}`, code: 403, denied: []string{"DISABLE_HOST_CHECK;db01"}},
+		{name: "host contact", user: "alice", path: "/disable_host_check", body: `{"hostname": "db01"}`, code: 200, commands: []string{"DISABLE_HOST_CHECK;db01"}},
+		{name: "catalogue", user: "alice", path: "/commands/DISABLE_HOST_CHECK", body: `{"host_name": "web01"}`, code: 403, denied: []string{"DISABLE_HOST_CHECK;web01"}},
+		{name: "batch", user: "alice", path: "/commands", body: `[{"command": "DISABLE_HOST_CHECK", "params": {"host_name": "web01"}}, {"command": "DISABLE_SVC_CHECK", "params": {"host_name": "web01", "service_description": "HTTP"}}]`, code: 207, commands: []string{"DISABLE_SVC_CHECK;web01;HTTP"}, denied: []string{"DISABLE_HOST_CHECK;web01"}},
+		{name: "passive check result", user: "alice", path: "/process_host_check_result", body: `{"hostname": "web01", "return_code": 0, "output": "UP"}`, code: 403, denied: []string{"PROCESS_HOST_CHECK_RESULT;web01;0;UP"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			api := newAuthorizedTestApi(t)
+			is.NoErr(api.EnableAuditLog(filepath.Join(filepath.Dir(api.fileCommand), "audit.log"), 0, 0))
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			api.router.ServeHTTP(w, auth.WithPrincipal(r, tt.user))
+
+			is.Equal(w.Code, tt.code)
+			is.Equal(strings.Join(writtenCommands(t, api), "\n"), strings.Join(tt.commands, "\n"))
+
+			records, err := api.auditLog.query(time.Time{}, time.Time{}, func(record *auditRecord) bool {
+				return strings.HasPrefix(record.Result, "error: ")
+			}, defaultAuditLimit)
+			is.NoErr(err)
+			var denied []string
+			for _, record := range records {
+				denied = append(denied, record.Command)
+			}
+			is.Equal(strings.Join(denied, "\n"), strings.Join(tt.denied, "\n"))
+		})
+	}
+}
+
+func TestAuthorizationDisabled(t *testing.T) {
+	is := is.New(t)
+	api := newAuthorizedTestApi(t)
+	api.cgiConfig.useAuthentication = false
+
+	r := auth.WithPrincipal(httptest.NewRequest(http.MethodPost, "/", nil), "eve")
+	is.NoErr(api.authorizeCommands(r, []string{"SHUTDOWN_PROGRAM"}))
+}
+
+// generateObjectCache returns an objects.cache defining hosts with services each, every tenth
+// host in the contact group of alice
+func generateObjectCache(hosts, services int) []byte {
+	var b bytes.Buffer
+	b.WriteString("define contact {\n\tcontact_name\talice\n\t}\n\n")
+	b.WriteString("define contactgroup {\n\tcontactgroup_name\tdba\n\tmembers\talice\n\t}\n\n")
+	for h := 0; h < hosts; h++ {
+		host := fmt.Sprintf("host%05d.example.com", h)
+		group := "ops"
+		if h%10 == 0 {
+			group = "dba"
+		}
+		fmt.Fprintf(&b, "define host {\n\thost_name\t%s\n\tcontact_groups\t%s\n\t}\n\n", host, group)
+		for s := 0; s < services; s++ {
+			fmt.Fprintf(&b, "define service {\n\thost_name\t%s\n\tservice_description\tService %d\n\tcontacts\tjason\n\t}\n\n", host, s)
+		}
+	}
+	return b.Bytes()
+}
+
+// BenchmarkAuthorizedServiceStatus lists the services of 1000 hosts with 40 services each as a
+// contact of a tenth of them
+func BenchmarkAuthorizedServiceStatus(b *testing.B) {
+	static, err := readObjectCache(bytes.NewReader(generateObjectCache(1000, 40)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	status, err := refreshStatusData(bytes.NewReader(generateStatusDat(1000, 40)))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	api := NewAPI(":0", "", "", "", nil, false)
+	api.publishStatic(static, time.Time{})
+	api.publishStatus(status, time.Time{})
+	if err := api.EnableAuthorization("testdata/cgi.cfg"); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/servicestatus", nil)
+		api.router.ServeHTTP(w, auth.WithPrincipal(r, "alice"))
+
+		var services []*ServiceStatus
+		if err := json.NewDecoder(w.Body).Decode(&services); err != nil {
+			b.Fatal(err)
+		}
+		if len(services) != 4000 {
+			b.Fatalf("listed %d services", len(services))
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	hostStates    = map[string]string{"UP": "0", "DOWN": "1", "UNREACHABLE": "2"}
+	serviceStates = map[string]string{"OK": "0", "WARNING": "1", "CRITICAL": "2", "UNKNOWN": "3"}
+	stateTypes    = map[string]string{"SOFT": "0", "HARD": "1"}
+)
+
+// statusFilter selects hosts or services from the current status data. Empty fields match everything.
+// The host_name and service_description patterns must match the whole name.
+type statusFilter struct {
+	// Object is "host" or "service" (the default)
+	Object             string            `json:"object"`
+	HostName           string            `json:"host_name"`
+	Hostgroup          string            `json:"hostgroup"`
+	ServiceDescription string            `json:"service_description"`
+	PluginOutput       string            `json:"plugin_output"`
+	State              []string          `json:"state"`
+	StateType          string            `json:"state_type"`
+	Acknowledged       *bool             `json:"acknowledged"`
+	CustomVariables    map[string]string `json:"custom_variables"`
+
+	hostName           *regexp.Regexp
+	serviceDescription *regexp.Regexp
+	pluginOutput       *regexp.Regexp
+	states             []string
+	stateType          string
+}
+
+// compile checks the filter and prepares its patterns and states for matching
+func (f *statusFilter) compile() error {
+	states := serviceStates
+	switch f.Object {
+	case "", "service":
+		f.Object = "service"
+	case "host":
+		states = hostStates
+		if f.ServiceDescription != "" {
+			return fmt.Errorf("service_description can not filter hosts")
+		}
+	default:
+		return fmt.Errorf("object must be host or service")
+	}
+
+	var err error
+	for _, pattern := range []struct {
+		name     string
+		value    string
+		re       **regexp.Regexp
+		anchored bool
+	}{
+		{"host_name", f.HostName, &f.hostName, true},
+		{"service_description", f.ServiceDescription, &f.serviceDescription, true},
+		{"plugin_output", f.PluginOutput, &f.pluginOutput, false},
+	} {
+		if pattern.value == "" {
+			continue
+		}
+		value := pattern.value
+		if pattern.anchored {
+			// So that db1 does not also select db10
+			value = "^(?:" + value + ")$"
+		}
+		if *pattern.re, err = regexp.Compile(value); err != nil {
+			return fmt.Errorf("invalid %s pattern: %s", pattern.name, err)
+		}
+	}
+
+	f.states = nil
+	for _, state := range f.State {
+		code, ok := states[strings.ToUpper(state)]
+		if !ok {
+			for _, c := range states {
+				if c == state {
+					code, ok = c, true
+				}
+			}
+		}
+		if !ok {
+			return fmt.Errorf("unknown %s state %s", f.Object, state)
+		}
+		f.states = append(f.states, code)
+	}
+
+	f.stateType = ""
+	if f.StateType != "" {
+		code, ok := stateTypes[strings.ToUpper(f.StateType)]
+		if !ok {
+			return fmt.Errorf("state_type must be soft or hard")
+		}
+		f.stateType = code
+	}
+	return nil
+}
+
+// empty reports whether the filter has no criteria and so matches every host or service
+func (f *statusFilter) empty() bool {
+	return f.HostName == "" && f.Hostgroup == "" && f.ServiceDescription == "" && f.PluginOutput == "" &&
+		len(f.State) == 0 && f.StateType == "" && f.Acknowledged == nil && len(f.CustomVariables) == 0
+}
+
+// match reports whether an object with the given attributes passes the filter
+func (f *statusFilter) match(host, service, output, state, stateType, acknowledged string, vars map[string]string, static *StaticData) bool {
+	if f.hostName != nil && !f.hostName.MatchString(host) {
+		return false
+	}
+	if f.serviceDescription != nil && !f.serviceDescription.MatchString(service) {
+		return false
+	}
+	if f.pluginOutput != nil && !f.pluginOutput.MatchString(output) {
+		return false
+	}
+	if f.states != nil && !stringInSlice(state, f.states) {
+		return false
+	}
+	if f.stateType != "" && f.stateType != stateType {
+		return false
+	}
+	if f.Acknowledged != nil && *f.Acknowledged != (acknowledged == "1") {
+		return false
+	}
+	for name, value := range f.CustomVariables {
+		// Nagios stores custom variable names upper case, without the leading underscore
+		if v, ok := vars[strings.ToUpper(strings.TrimPrefix(name, "_"))]; !ok || v != value {
+			return false
+		}
+	}
+	if f.Hostgroup != "" && !static.inHostgroup(f.Hostgroup, host) {
+		return false
+	}
+	return true
+}
+
+type bulkMatch struct {
+	HostName           string `json:"host_name"`
+	ServiceDescription string `json:"service_description,omitempty"`
+}
+
+// matches returns the hosts or services in the current status data passing the filter that
+// the caller of r is authorized to see
+func (a *Api) matches(r *http.Request, f *statusFilter) []bulkMatch {
+	snap := a.snapshotOf(r)
+
+	z := a.authorization(r)
+
+	var matches []bulkMatch
+	if f.Object == "host" {
+		for _, h := range snap.status.Hosts {
+			if z.canSeeHost(h.HostName) && f.match(h.HostName, "", h.PluginOutput, h.CurrentState, h.StateType, h.ProblemHasBeenAcknowledged, h.CustomVariables, snap.static) {
+				matches = append(matches, bulkMatch{HostName: h.HostName})
+			}
+		}
+		return matches
+	}
+
+	for _, s := range snap.status.Services {
+		if z.canSeeService(s.HostName, s.ServiceDescription) && f.match(s.HostName, s.ServiceDescription, s.PluginOutput, s.CurrentState, s.StateType, s.ProblemHasBeenAcknowledged, s.CustomVariables, snap.static) {
+			matches = append(matches, bulkMatch{HostName: s.HostName, ServiceDescription: s.ServiceDescription})
+		}
+	}
+	return matches
+}
+
+type bulkResult struct {
+	Preview  bool        `json:"preview"`
+	DryRun   bool        `json:"dry_run,omitempty"`
+	Matches  []bulkMatch `json:"matches"`
+	Commands []string    `json:"commands"`
+}
+
+// HandleBulkCommand runs a catalogue command for every host or service matching a filter over
+// the current status data. The filter fills in host_name and service_description, params holds
+// the other command parameters. With preview set the matches and commands are returned but
+// nothing is written, dry run also returns them as the lines nagios command file would get.
+// Commands are only written when all of them are valid. A filter without criteria is only
+// written with all set, so a forgotten filter does not target the whole installation.
+// POST: /bulk_command
+//       {"filter": {<statusFilter>}, "command": <COMMAND_NAME>, "params": {...}, "preview": <bool>, "all": <bool>}
+func (a *Api) HandleBulkCommand(w http.ResponseWriter, r *http.Request) {
+	var bulk struct {
+		Filter  statusFilter
+		Command string
+		Params  map[string]interface{}
+		Preview bool
+		All     bool
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&bulk); err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := bulk.Filter.compile(); err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	spec, ok := commandCatalogue[bulk.Command]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Error: Unknown command %s", bulk.Command), http.StatusNotFound)
+		return
+	}
+
+	// The filter supplies exactly the targets the command needs
+	targets := []string{"host_name"}
+	if bulk.Filter.Object == "service" {
+		targets = append(targets, "service_description")
+	}
+	for _, target := range targets {
+		found := false
+		for _, p := range spec.Params {
+			found = found || p.Name == target
+		}
+		if !found {
+			http.Error(w, fmt.Sprintf("Error: %s does not take the %s of a %s", spec.Name, target, bulk.Filter.Object), http.StatusBadRequest)
+			return
+		}
+		if _, ok := bulk.Params[target]; ok {
+			http.Error(w, fmt.Sprintf("Error: %s is set by the filter", target), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !bulk.Preview && !bulk.All && bulk.Filter.empty() {
+		http.Error(w, fmt.Sprintf("Error: the filter matches every %s, set \"all\": true to run %s on all of them", bulk.Filter.Object, spec.Name), http.StatusBadRequest)
+		return
+	}
+
+	result := bulkResult{Preview: bulk.Preview, Matches: a.matches(r, &bulk.Filter), Commands: []string{}}
+	if result.Matches == nil {
+		result.Matches = []bulkMatch{}
+	}
+
+	for _, m := range result.Matches {
+		params := map[string]interface{}{"host_name": m.HostName}
+		if m.ServiceDescription != "" {
+			params["service_description"] = m.ServiceDescription
+		}
+		for name, value := range bulk.Params {
+			params[name] = value
+		}
+
+		command, _, code, err := a.prepareCommand(r, spec.Name, params)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error: %s", err), code)
+			return
+		}
+		result.Commands = append(result.Commands, command)
+	}
+
+	if !bulk.Preview && len(result.Commands) > 0 {
+		dryRun, err := a.submitCommands(r, result.Commands)
+		if err != nil {
+			replyCommandError(w, err)
+			return
+		}
+		if dryRun {
+			result.DryRun = true
+			result.Commands = commandLines(result.Commands)
+		}
+	} else if a.dryRun(r) {
+		result.DryRun = true
+		result.Commands = commandLines(result.Commands)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+)
+
+func newBulkTestApi(t *testing.T) *Api {
+	api := newTestApi(t)
+	data := NewStatusData()
+	data.Hosts = []*HostStatus{
+		{HostName: "web01", CurrentState: "0", StateType: "1"},
+		{HostName: "db01", CurrentState: "1", StateType: "1", PluginOutput: "PING CRITICAL"},
+	}
+	data.Services = []*ServiceStatus{
+		{HostName: "web01", ServiceDescription: "HTTP", CurrentState: "2", StateType: "1", PluginOutput: "HTTP CRITICAL - connection refused"},
+		{HostName: "db01", ServiceDescription: "MySQL", CurrentState: "2", StateType: "0", PluginOutput: "MySQL CRITICAL - disk full", ProblemHasBeenAcknowledged: "1"},
+		{HostName: "db01", ServiceDescription: "Disk", CurrentState: "2", StateType: "1", PluginOutput: "DISK CRITICAL - /var 99%", CustomVariables: map[string]string{"OWNER": "dba"}},
+	}
+	api.publishStatus(data, time.Time{})
+	return api
+}
+
+func TestBulkCommand(t *testing.T) {
+	ack := `"command": "ACKNOWLEDGE_SVC_PROBLEM", "params": {"author": "jason", "comment": "disk"}`
+
+	tests := []struct {
+		name     string
+		body     string
+		code     int
+		matches  []string
+		commands []string
+		written  bool
+	}{
+		{
+			name:     "state, output and hostgroup",
+			body:     `{"filter": {"state": ["CRITICAL"], "plugin_output": "(?i)disk", "hostgroup": "db-servers"}, ` + ack + `}`,
+			code:     200,
+			matches:  []string{"db01/MySQL", "db01/Disk"},
+			commands: []string{"ACKNOWLEDGE_SVC_PROBLEM;db01;MySQL;2;1;1;jason;disk", "ACKNOWLEDGE_SVC_PROBLEM;db01;Disk;2;1;1;jason;disk"},
+			written:  true,
+		},
+		{
+			name:     "preview",
+			body:     `{"filter": {"state": ["2"], "state_type": "hard", "acknowledged": false}, ` + ack + `, "preview": true}`,
+			code:     200,
+			matches:  []string{"web01/HTTP", "db01/Disk"},
+			commands: []string{"ACKNOWLEDGE_SVC_PROBLEM;web01;HTTP;2;1;1;jason;disk", "ACKNOWLEDGE_SVC_PROBLEM;db01;Disk;2;1;1;jason;disk"},
+		},
+		{
+			name:     "custom variable and service pattern",
+			body:     `{"filter": {"service_description": "Di.*", "custom_variables": {"_owner": "dba"}}, ` + ack + `}`,
+			code:     200,
+			matches:  []string{"db01/Disk"},
+			commands: []string{"ACKNOWLEDGE_SVC_PROBLEM;db01;Disk;2;1;1;jason;disk"},
+			written:  true,
+		},
+		{
+			name:     "hosts",
+			body:     `{"filter": {"object": "host", "state": ["down", "unreachable"]}, "command": "DISABLE_HOST_NOTIFICATIONS"}`,
+			code:     200,
+			matches:  []string{"db01/"},
+			commands: []string{"DISABLE_HOST_NOTIFICATIONS;db01"},
+			written:  true,
+		},
+		{name: "no match", body: `{"filter": {"host_name": "mail.*"}, ` + ack + `}`, code: 200},
+		{name: "unknown command", body: `{"filter": {}, "command": "NOPE"}`, code: 404},
+		{name: "command without service", body: `{"filter": {}, "command": "DISABLE_HOST_CHECK"}`, code: 400},
+		{name: "target in params", body: `{"filter": {"object": "host"}, "command": "DISABLE_HOST_CHECK", "params": {"host_name": "web01"}}`, code: 400},
+		{name: "invalid pattern", body: `{"filter": {"plugin_output": "("}, ` + ack + `}`, code: 400},
+		{name: "unknown state", body: `{"filter": {"object": "host", "state": ["CRITICAL"]}, "command": "DISABLE_HOST_CHECK"}`, code: 400},
+		{name: "invalid state type", body: `{"filter": {"state_type": "firm"}, ` + ack + `}`, code: 400},
+		{name: "missing parameter", body: `{"filter": {"state": ["CRITICAL"]}, "command": "ACKNOWLEDGE_SVC_PROBLEM"}`, code: 400},
+		{name: "whole host name", body: `{"filter": {"object": "host", "host_name": "db0|web"}, "command": "DISABLE_HOST_NOTIFICATIONS"}`, code: 200},
+		{name: "empty filter", body: `{"filter": {}, "command": "DISABLE_SVC_NOTIFICATIONS"}`, code: 400},
+		{
+			name:     "empty filter preview",
+			body:     `{"filter": {"object": "host"}, "command": "DISABLE_HOST_NOTIFICATIONS", "preview": true}`,
+			code:     200,
+			matches:  []string{"web01/", "db01/"},
+			commands: []string{"DISABLE_HOST_NOTIFICATIONS;web01", "DISABLE_HOST_NOTIFICATIONS;db01"},
+		},
+		{
+			name:     "empty filter with all",
+			body:     `{"filter": {"object": "host"}, "command": "DISABLE_HOST_NOTIFICATIONS", "all": true}`,
+			code:     200,
+			matches:  []string{"web01/", "db01/"},
+			commands: []string{"DISABLE_HOST_NOTIFICATIONS;web01", "DISABLE_HOST_NOTIFICATIONS;db01"},
+			written:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			api := newBulkTestApi(t)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/bulk_command", strings.NewReader(tt.body))
+			api.router.ServeHTTP(w, r)
+
+			is.Equal(w.Code, tt.code)
+			written := writtenCommands(t, api)
+			if tt.written {
+				is.Equal(strings.Join(written, "\n"), strings.Join(tt.commands, "\n"))
+			} else {
+				is.Equal(len(written), 0)
+			}
+			if tt.code != 200 {
+				return
+			}
+
+			var result bulkResult
+			is.NoErr(json.NewDecoder(w.Body).Decode(&result))
+			var matches []string
+			for _, m := range result.Matches {
+				matches = append(matches, m.HostName+"/"+m.ServiceDescription)
+			}
+			is.Equal(strings.Join(matches, ","), strings.Join(tt.matches, ","))
+			is.Equal(strings.Join(result.Commands, "\n"), strings.Join(tt.commands, "\n"))
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// batchCommand is one item of a POST /commands request
+type batchCommand struct {
+	Command string                 `json:"command"`
+	Params  map[string]interface{} `json:"params"`
+}
+
+// batchResult reports the outcome of one item of a POST /commands request, in request order
+type batchResult struct {
+	Command string `json:"command"`
+	Status  int    `json:"status"`
+	Error   string `json:"error,omitempty"`
+	// Line is the command line that would have been written, in dry run only
+	Line string `json:"line,omitempty"`
+}
+
+// HandleBatchCommands validates a list of catalogue commands and writes the valid ones to
+// nagios command file in a single append. The reply lists the result of every item; it is
+// 200 when all of them were written and 207 when some were rejected. In dry run nothing is
+// written and the result of each valid item carries the line that would have been.
+// POST: /commands
+//       [{"command": <COMMAND_NAME>, "params": {<param name>: <value>, ...}}, ...]
+func (a *Api) HandleBatchCommands(w http.ResponseWriter, r *http.Request) {
+	var batch []batchCommand
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&batch); err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusBadRequest)
+		return
+	}
+	if len(batch) == 0 {
+		http.Error(w, "Error: at least one command is required", http.StatusBadRequest)
+		return
+	}
+
+	var commands []string
+	var written []int
+	results := make([]batchResult, len(batch))
+	for i, item := range batch {
+		results[i].Command = item.Command
+
+		command, _, code, err := a.prepareCommand(r, item.Command, item.Params)
+		results[i].Status = code
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		commands = append(commands, command)
+		written = append(written, i)
+	}
+
+	status := http.StatusOK
+	if len(commands) != len(batch) {
+		status = http.StatusMultiStatus
+	}
+
+	if len(commands) > 0 {
+		dryRun, err := a.submitCommands(r, commands)
+		if err != nil {
+			status = http.StatusInternalServerError
+			for _, i := range written {
+				results[i].Status = http.StatusInternalServerError
+				results[i].Error = "Could not execute command"
+			}
+		}
+		if dryRun {
+			for n, line := range commandLines(commands) {
+				results[written[n]].Line = line
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(results)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+)
+
+func TestBatchCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		code     int
+		statuses []int
+		commands []string
+	}{
+		{
+			name:     "all valid",
+			body:     `[{"command": "DISABLE_HOST_CHECK", "params": {"host_name": "web01"}}, {"command": "ACKNOWLEDGE_HOST_PROBLEM", "params": {"host_name": "db01", "author": "jason", "comment": "rack down"}}]`,
+			code:     200,
+			statuses: []int{200, 200},
+			commands: []string{"DISABLE_HOST_CHECK;web01", "ACKNOWLEDGE_HOST_PROBLEM;db01;2;1;1;jason;rack down"},
+		},
+		{
+			name:     "partial failure",
+			body:     `[{"command": "DISABLE_HOST_CHECK", "params": {"host_name": "web01"}}, {"command": "DISABLE_HOST_CHECK", "params": {"host_name": "nope"}}, {"command": "NOPE"}, {"command": "DISABLE_HOST_CHECK"}, {"command": "ENABLE_NOTIFICATIONS"}]`,
+			code:     207,
+			statuses: []int{200, 404, 404, 400, 200},
+			commands: []string{"DISABLE_HOST_CHECK;web01", "ENABLE_NOTIFICATIONS"},
+		},
+		{
+			name:     "all invalid",
+			body:     `[{"command": "DISABLE_HOST_CHECK", "params": {"host_name": "web01\nSHUTDOWN_PROGRAM"}}]`,
+			code:     207,
+			statuses: []int{404},
+		},
+		{name: "empty batch", body: `[]`, code: 400},
+		{name: "not a list", body: `{"command": "ENABLE_NOTIFICATIONS"}`, code: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			api := newTestApi(t)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/commands", strings.NewReader(tt.body))
+			api.router.ServeHTTP(w, r)
+
+			is.Equal(w.Code, tt.code)
+			is.Equal(strings.Join(writtenCommands(t, api), "\n"), strings.Join(tt.commands, "\n"))
+			if tt.statuses == nil {
+				return
+			}
+
+			var results []batchResult
+			is.NoErr(json.NewDecoder(w.Body).Decode(&results))
+			is.Equal(len(results), len(tt.statuses))
+			for i, result := range results {
+				is.Equal(result.Status, tt.statuses[i])
+				is.Equal(result.Error == "", tt.statuses[i] == 200)
+			}
+		})
+	}
+}
+
+func TestBatchCommandsWriteFailure(t *testing.T) {
+	is := is.New(t)
+	api := newTestApi(t)
+	is.NoErr(os.Remove(api.fileCommand))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/commands", strings.NewReader(`[{"command": "ENABLE_NOTIFICATIONS"}, {"command": "NOPE"}]`))
+	api.router.ServeHTTP(w, r)
+
+	is.Equal(w.Code, 500)
+	var results []batchResult
+	is.NoErr(json.NewDecoder(w.Body).Decode(&results))
+	is.Equal(results[0].Status, 500)
+	is.Equal(results[1].Status, 404)
+}
@@ -0,0 +1,501 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Catalogue of Nagios external commands served by POST /commands/<COMMAND_NAME>.
+// See https://old.nagios.org/developerinfo/externalcommands/commandlist.php
+
+// Parameter types
+const (
+	paramString    = "string"
+	paramText      = "text"
+	paramInt       = "int"
+	paramBool      = "bool"
+	paramTimestamp = "timestamp"
+)
+
+// Target object kinds, checked against objects.cache before a command is written
+const (
+	targetHost         = "host"
+	targetService      = "service"
+	targetHostgroup    = "hostgroup"
+	targetServicegroup = "servicegroup"
+	targetContact      = "contact"
+	targetContactgroup = "contactgroup"
+)
+
+// defaultNow is the default of timestamp parameters that mean "as soon as possible"
+const defaultNow = "now"
+
+type commandParam struct {
+	Name     string      `json:"name"`
+	Type     string      `json:"type"`
+	Target   string      `json:"target,omitempty"`
+	Default  interface{} `json:"default,omitempty"`
+	Optional bool        `json:"optional,omitempty"`
+}
+
+type commandSpec struct {
+	Name   string         `json:"name"`
+	Params []commandParam `json:"params"`
+}
+
+var (
+	hostNameParam         = commandParam{Name: "host_name", Type: paramString, Target: targetHost}
+	serviceParam          = commandParam{Name: "service_description", Type: paramString, Target: targetService}
+	hostgroupParam        = commandParam{Name: "hostgroup_name", Type: paramString, Target: targetHostgroup}
+	servicegroupParam     = commandParam{Name: "servicegroup_name", Type: paramString, Target: targetServicegroup}
+	contactParam          = commandParam{Name: "contact_name", Type: paramString, Target: targetContact}
+	contactgroupParam     = commandParam{Name: "contactgroup_name", Type: paramString, Target: targetContactgroup}
+	stickyParam           = commandParam{Name: "sticky", Type: paramInt, Default: 2}
+	notifyParam           = commandParam{Name: "notify", Type: paramBool, Default: true}
+	persistentParam       = commandParam{Name: "persistent", Type: paramBool, Default: true}
+	authorParam           = commandParam{Name: "author", Type: paramString}
+	commentParam          = commandParam{Name: "comment", Type: paramText}
+	checkTimeParam        = commandParam{Name: "check_time", Type: paramTimestamp, Default: defaultNow}
+	commentIDParam        = commandParam{Name: "comment_id", Type: paramInt}
+	downtimeIDParam       = commandParam{Name: "downtime_id", Type: paramInt}
+	timeperiodParam       = commandParam{Name: "timeperiod", Type: paramString}
+	checkCommandParam     = commandParam{Name: "check_command", Type: paramString}
+	eventHandlerParam     = commandParam{Name: "event_handler_command", Type: paramString}
+	modattrParam          = commandParam{Name: "value", Type: paramInt}
+	checkAttemptsParam    = commandParam{Name: "check_attempts", Type: paramInt}
+	checkIntervalParam    = commandParam{Name: "check_interval", Type: paramInt}
+	notificationTimeParam = commandParam{Name: "notification_time", Type: paramTimestamp}
+	notificationNumParam  = commandParam{Name: "notification_number", Type: paramInt}
+	varnameParam          = commandParam{Name: "varname", Type: paramString}
+	varvalueParam         = commandParam{Name: "varvalue", Type: paramText}
+	optionsParam          = commandParam{Name: "options", Type: paramInt, Default: 0}
+	returnCodeParam       = commandParam{Name: "return_code", Type: paramInt}
+	pluginOutputParam     = commandParam{Name: "plugin_output", Type: paramText}
+	expireTimeParam       = commandParam{Name: "expire_time", Type: paramTimestamp}
+
+	// downtimeParams follow the target of every SCHEDULE_*_DOWNTIME command
+	downtimeParams = []commandParam{
+		{Name: "start_time", Type: paramTimestamp},
+		{Name: "end_time", Type: paramTimestamp},
+		{Name: "fixed", Type: paramBool, Default: true},
+		{Name: "trigger_id", Type: paramInt, Default: 0},
+		{Name: "duration", Type: paramInt},
+		authorParam,
+		commentParam,
+	}
+)
+
+func params(p ...commandParam) []commandParam {
+	return p
+}
+
+func optional(p commandParam) commandParam {
+	p.Optional = true
+	return p
+}
+
+func withDowntime(p ...commandParam) []commandParam {
+	return append(p, downtimeParams...)
+}
+
+// PROCESS_FILE is deliberately left out: it makes Nagios read and optionally delete arbitrary files
+var commandList = []commandSpec{
+	{"ACKNOWLEDGE_HOST_PROBLEM", params(hostNameParam, stickyParam, notifyParam, persistentParam, authorParam, commentParam)},
+	{"ACKNOWLEDGE_HOST_PROBLEM_EXPIRE", params(hostNameParam, stickyParam, notifyParam, persistentParam, expireTimeParam, authorParam, commentParam)},
+	{"ACKNOWLEDGE_SVC_PROBLEM", params(hostNameParam, serviceParam, stickyParam, notifyParam, persistentParam, authorParam, commentParam)},
+	{"ACKNOWLEDGE_SVC_PROBLEM_EXPIRE", params(hostNameParam, serviceParam, stickyParam, notifyParam, persistentParam, expireTimeParam, authorParam, commentParam)},
+	{"ADD_HOST_COMMENT", params(hostNameParam, persistentParam, authorParam, commentParam)},
+	{"ADD_SVC_COMMENT", params(hostNameParam, serviceParam, persistentParam, authorParam, commentParam)},
+	{"CHANGE_CONTACT_HOST_NOTIFICATION_TIMEPERIOD", params(contactParam, timeperiodParam)},
+	{"CHANGE_CONTACT_MODATTR", params(contactParam, modattrParam)},
+	{"CHANGE_CONTACT_MODHATTR", params(contactParam, modattrParam)},
+	{"CHANGE_CONTACT_MODSATTR", params(contactParam, modattrParam)},
+	{"CHANGE_CONTACT_SVC_NOTIFICATION_TIMEPERIOD", params(contactParam, timeperiodParam)},
+	{"CHANGE_CUSTOM_CONTACT_VAR", params(contactParam, varnameParam, varvalueParam)},
+	{"CHANGE_CUSTOM_HOST_VAR", params(hostNameParam, varnameParam, varvalueParam)},
+	{"CHANGE_CUSTOM_SVC_VAR", params(hostNameParam, serviceParam, varnameParam, varvalueParam)},
+	{"CHANGE_GLOBAL_HOST_EVENT_HANDLER", params(eventHandlerParam)},
+	{"CHANGE_GLOBAL_SVC_EVENT_HANDLER", params(eventHandlerParam)},
+	{"CHANGE_HOST_CHECK_COMMAND", params(hostNameParam, checkCommandParam)},
+	{"CHANGE_HOST_CHECK_TIMEPERIOD", params(hostNameParam, timeperiodParam)},
+	{"CHANGE_HOST_EVENT_HANDLER", params(hostNameParam, eventHandlerParam)},
+	{"CHANGE_HOST_MODATTR", params(hostNameParam, modattrParam)},
+	{"CHANGE_HOST_NOTIFICATION_TIMEPERIOD", params(hostNameParam, timeperiodParam)},
+	{"CHANGE_MAX_HOST_CHECK_ATTEMPTS", params(hostNameParam, checkAttemptsParam)},
+	{"CHANGE_MAX_SVC_CHECK_ATTEMPTS", params(hostNameParam, serviceParam, checkAttemptsParam)},
+	{"CHANGE_NORMAL_HOST_CHECK_INTERVAL", params(hostNameParam, checkIntervalParam)},
+	{"CHANGE_NORMAL_SVC_CHECK_INTERVAL", params(hostNameParam, serviceParam, checkIntervalParam)},
+	{"CHANGE_RETRY_HOST_CHECK_INTERVAL", params(hostNameParam, checkIntervalParam)},
+	{"CHANGE_RETRY_SVC_CHECK_INTERVAL", params(hostNameParam, serviceParam, checkIntervalParam)},
+	{"CHANGE_SVC_CHECK_COMMAND", params(hostNameParam, serviceParam, checkCommandParam)},
+	{"CHANGE_SVC_CHECK_TIMEPERIOD", params(hostNameParam, serviceParam, timeperiodParam)},
+	{"CHANGE_SVC_EVENT_HANDLER", params(hostNameParam, serviceParam, eventHandlerParam)},
+	{"CHANGE_SVC_MODATTR", params(hostNameParam, serviceParam, modattrParam)},
+	{"CHANGE_SVC_NOTIFICATION_TIMEPERIOD", params(hostNameParam, serviceParam, timeperiodParam)},
+	{"CLEAR_HOST_FLAPPING_STATE", params(hostNameParam)},
+	{"CLEAR_SVC_FLAPPING_STATE", params(hostNameParam, serviceParam)},
+	{"DELAY_HOST_NOTIFICATION", params(hostNameParam, notificationTimeParam)},
+	{"DELAY_SVC_NOTIFICATION", params(hostNameParam, serviceParam, notificationTimeParam)},
+	{"DEL_ALL_HOST_COMMENTS", params(hostNameParam)},
+	{"DEL_ALL_SVC_COMMENTS", params(hostNameParam, serviceParam)},
+	{"DEL_DOWNTIME_BY_HOSTGROUP_NAME", params(hostgroupParam, optional(hostNameParam), optional(serviceParam), optional(commandParam{Name: "start_time", Type: paramTimestamp}), optional(commandParam{Name: "comment", Type: paramString}))},
+	{"DEL_DOWNTIME_BY_HOST_NAME", params(hostNameParam, optional(serviceParam), optional(commandParam{Name: "start_time", Type: paramTimestamp}), optional(commandParam{Name: "comment", Type: paramString}))},
+	{"DEL_DOWNTIME_BY_START_TIME_COMMENT", params(commandParam{Name: "start_time", Type: paramTimestamp}, optional(commandParam{Name: "comment", Type: paramString}))},
+	{"DEL_HOST_COMMENT", params(commentIDParam)},
+	{"DEL_HOST_DOWNTIME", params(downtimeIDParam)},
+	{"DEL_SVC_COMMENT", params(commentIDParam)},
+	{"DEL_SVC_DOWNTIME", params(downtimeIDParam)},
+	{"DISABLE_ALL_NOTIFICATIONS_BEYOND_HOST", params(hostNameParam)},
+	{"DISABLE_CONTACTGROUP_HOST_NOTIFICATIONS", params(contactgroupParam)},
+	{"DISABLE_CONTACTGROUP_SVC_NOTIFICATIONS", params(contactgroupParam)},
+	{"DISABLE_CONTACT_HOST_NOTIFICATIONS", params(contactParam)},
+	{"DISABLE_CONTACT_SVC_NOTIFICATIONS", params(contactParam)},
+	{"DISABLE_EVENT_HANDLERS", nil},
+	{"DISABLE_FLAP_DETECTION", nil},
+	{"DISABLE_HOSTGROUP_HOST_CHECKS", params(hostgroupParam)},
+	{"DISABLE_HOSTGROUP_HOST_NOTIFICATIONS", params(hostgroupParam)},
+	{"DISABLE_HOSTGROUP_PASSIVE_HOST_CHECKS", params(hostgroupParam)},
+	{"DISABLE_HOSTGROUP_PASSIVE_SVC_CHECKS", params(hostgroupParam)},
+	{"DISABLE_HOSTGROUP_SVC_CHECKS", params(hostgroupParam)},
+	{"DISABLE_HOSTGROUP_SVC_NOTIFICATIONS", params(hostgroupParam)},
+	{"DISABLE_HOST_AND_CHILD_NOTIFICATIONS", params(hostNameParam)},
+	{"DISABLE_HOST_CHECK", params(hostNameParam)},
+	{"DISABLE_HOST_EVENT_HANDLER", params(hostNameParam)},
+	{"DISABLE_HOST_FLAP_DETECTION", params(hostNameParam)},
+	{"DISABLE_HOST_FRESHNESS_CHECKS", nil},
+	{"DISABLE_HOST_NOTIFICATIONS", params(hostNameParam)},
+	{"DISABLE_HOST_SVC_CHECKS", params(hostNameParam)},
+	{"DISABLE_HOST_SVC_NOTIFICATIONS", params(hostNameParam)},
+	{"DISABLE_NOTIFICATIONS", nil},
+	{"DISABLE_NOTIFICATIONS_EXPIRE_TIME", params(commandParam{Name: "schedule_time", Type: paramTimestamp, Default: defaultNow}, expireTimeParam)},
+	{"DISABLE_PASSIVE_HOST_CHECKS", params(hostNameParam)},
+	{"DISABLE_PASSIVE_SVC_CHECKS", params(hostNameParam, serviceParam)},
+	{"DISABLE_PERFORMANCE_DATA", nil},
+	{"DISABLE_SERVICEGROUP_HOST_CHECKS", params(servicegroupParam)},
+	{"DISABLE_SERVICEGROUP_HOST_NOTIFICATIONS", params(servicegroupParam)},
+	{"DISABLE_SERVICEGROUP_PASSIVE_HOST_CHECKS", params(servicegroupParam)},
+	{"DISABLE_SERVICEGROUP_PASSIVE_SVC_CHECKS", params(servicegroupParam)},
+	{"DISABLE_SERVICEGROUP_SVC_CHECKS", params(servicegroupParam)},
+	{"DISABLE_SERVICEGROUP_SVC_NOTIFICATIONS", params(servicegroupParam)},
+	{"DISABLE_SERVICE_FRESHNESS_CHECKS", nil},
+	{"DISABLE_SVC_CHECK", params(hostNameParam, serviceParam)},
+	{"DISABLE_SVC_EVENT_HANDLER", params(hostNameParam, serviceParam)},
+	{"DISABLE_SVC_FLAP_DETECTION", params(hostNameParam, serviceParam)},
+	{"DISABLE_SVC_NOTIFICATIONS", params(hostNameParam, serviceParam)},
+	{"ENABLE_ALL_NOTIFICATIONS_BEYOND_HOST", params(hostNameParam)},
+	{"ENABLE_CONTACTGROUP_HOST_NOTIFICATIONS", params(contactgroupParam)},
+	{"ENABLE_CONTACTGROUP_SVC_NOTIFICATIONS", params(contactgroupParam)},
+	{"ENABLE_CONTACT_HOST_NOTIFICATIONS", params(contactParam)},
+	{"ENABLE_CONTACT_SVC_NOTIFICATIONS", params(contactParam)},
+	{"ENABLE_EVENT_HANDLERS", nil},
+	{"ENABLE_FLAP_DETECTION", nil},
+	{"ENABLE_HOSTGROUP_HOST_CHECKS", params(hostgroupParam)},
+	{"ENABLE_HOSTGROUP_HOST_NOTIFICATIONS", params(hostgroupParam)},
+	{"ENABLE_HOSTGROUP_PASSIVE_HOST_CHECKS", params(hostgroupParam)},
+	{"ENABLE_HOSTGROUP_PASSIVE_SVC_CHECKS", params(hostgroupParam)},
+	{"ENABLE_HOSTGROUP_SVC_CHECKS", params(hostgroupParam)},
+	{"ENABLE_HOSTGROUP_SVC_NOTIFICATIONS", params(hostgroupParam)},
+	{"ENABLE_HOST_AND_CHILD_NOTIFICATIONS", params(hostNameParam)},
+	{"ENABLE_HOST_CHECK", params(hostNameParam)},
+	{"ENABLE_HOST_EVENT_HANDLER", params(hostNameParam)},
+	{"ENABLE_HOST_FLAP_DETECTION", params(hostNameParam)},
+	{"ENABLE_HOST_FRESHNESS_CHECKS", nil},
+	{"ENABLE_HOST_NOTIFICATIONS", params(hostNameParam)},
+	{"ENABLE_HOST_SVC_CHECKS", params(hostNameParam)},
+	{"ENABLE_HOST_SVC_NOTIFICATIONS", params(hostNameParam)},
+	{"ENABLE_NOTIFICATIONS", nil},
+	{"ENABLE_PASSIVE_HOST_CHECKS", params(hostNameParam)},
+	{"ENABLE_PASSIVE_SVC_CHECKS", params(hostNameParam, serviceParam)},
+	{"ENABLE_PERFORMANCE_DATA", nil},
+	{"ENABLE_SERVICEGROUP_HOST_CHECKS", params(servicegroupParam)},
+	{"ENABLE_SERVICEGROUP_HOST_NOTIFICATIONS", params(servicegroupParam)},
+	{"ENABLE_SERVICEGROUP_PASSIVE_HOST_CHECKS", params(servicegroupParam)},
+	{"ENABLE_SERVICEGROUP_PASSIVE_SVC_CHECKS", params(servicegroupParam)},
+	{"ENABLE_SERVICEGROUP_SVC_CHECKS", params(servicegroupParam)},
+	{"ENABLE_SERVICEGROUP_SVC_NOTIFICATIONS", params(servicegroupParam)},
+	{"ENABLE_SERVICE_FRESHNESS_CHECKS", nil},
+	{"ENABLE_SVC_CHECK", params(hostNameParam, serviceParam)},
+	{"ENABLE_SVC_EVENT_HANDLER", params(hostNameParam, serviceParam)},
+	{"ENABLE_SVC_FLAP_DETECTION", params(hostNameParam, serviceParam)},
+	{"ENABLE_SVC_NOTIFICATIONS", params(hostNameParam, serviceParam)},
+	{"PROCESS_HOST_CHECK_RESULT", params(hostNameParam, returnCodeParam, pluginOutputParam)},
+	{"PROCESS_SERVICE_CHECK_RESULT", params(hostNameParam, serviceParam, returnCodeParam, pluginOutputParam)},
+	{"READ_STATE_INFORMATION", nil},
+	{"REMOVE_HOST_ACKNOWLEDGEMENT", params(hostNameParam)},
+	{"REMOVE_SVC_ACKNOWLEDGEMENT", params(hostNameParam, serviceParam)},
+	{"RESTART_PROGRAM", nil},
+	{"SAVE_STATE_INFORMATION", nil},
+	{"SCHEDULE_AND_PROPAGATE_HOST_DOWNTIME", withDowntime(hostNameParam)},
+	{"SCHEDULE_AND_PROPAGATE_TRIGGERED_HOST_DOWNTIME", withDowntime(hostNameParam)},
+	{"SCHEDULE_FORCED_HOST_CHECK", params(hostNameParam, checkTimeParam)},
+	{"SCHEDULE_FORCED_HOST_SVC_CHECKS", params(hostNameParam, checkTimeParam)},
+	{"SCHEDULE_FORCED_SVC_CHECK", params(hostNameParam, serviceParam, checkTimeParam)},
+	{"SCHEDULE_HOSTGROUP_HOST_DOWNTIME", withDowntime(hostgroupParam)},
+	{"SCHEDULE_HOSTGROUP_SVC_DOWNTIME", withDowntime(hostgroupParam)},
+	{"SCHEDULE_HOST_CHECK", params(hostNameParam, checkTimeParam)},
+	{"SCHEDULE_HOST_DOWNTIME", withDowntime(hostNameParam)},
+	{"SCHEDULE_HOST_SVC_CHECKS", params(hostNameParam, checkTimeParam)},
+	{"SCHEDULE_HOST_SVC_DOWNTIME", withDowntime(hostNameParam)},
+	{"SCHEDULE_SERVICEGROUP_HOST_DOWNTIME", withDowntime(servicegroupParam)},
+	{"SCHEDULE_SERVICEGROUP_SVC_DOWNTIME", withDowntime(servicegroupParam)},
+	{"SCHEDULE_SVC_CHECK", params(hostNameParam, serviceParam, checkTimeParam)},
+	{"SCHEDULE_SVC_DOWNTIME", withDowntime(hostNameParam, serviceParam)},
+	{"SEND_CUSTOM_HOST_NOTIFICATION", params(hostNameParam, optionsParam, authorParam, commentParam)},
+	{"SEND_CUSTOM_SVC_NOTIFICATION", params(hostNameParam, serviceParam, optionsParam, authorParam, commentParam)},
+	{"SET_HOST_NOTIFICATION_NUMBER", params(hostNameParam, notificationNumParam)},
+	{"SET_SVC_NOTIFICATION_NUMBER", params(hostNameParam, serviceParam, notificationNumParam)},
+	{"SHUTDOWN_PROGRAM", nil},
+	{"START_ACCEPTING_PASSIVE_HOST_CHECKS", nil},
+	{"START_ACCEPTING_PASSIVE_SVC_CHECKS", nil},
+	{"START_EXECUTING_HOST_CHECKS", nil},
+	{"START_EXECUTING_SVC_CHECKS", nil},
+	{"START_OBSESSING_OVER_HOST", params(hostNameParam)},
+	{"START_OBSESSING_OVER_HOST_CHECKS", nil},
+	{"START_OBSESSING_OVER_SVC", params(hostNameParam, serviceParam)},
+	{"START_OBSESSING_OVER_SVC_CHECKS", nil},
+	{"STOP_ACCEPTING_PASSIVE_HOST_CHECKS", nil},
+	{"STOP_ACCEPTING_PASSIVE_SVC_CHECKS", nil},
+	{"STOP_EXECUTING_HOST_CHECKS", nil},
+	{"STOP_EXECUTING_SVC_CHECKS", nil},
+	{"STOP_OBSESSING_OVER_HOST", params(hostNameParam)},
+	{"STOP_OBSESSING_OVER_HOST_CHECKS", nil},
+	{"STOP_OBSESSING_OVER_SVC", params(hostNameParam, serviceParam)},
+	{"STOP_OBSESSING_OVER_SVC_CHECKS", nil},
+}
+
+var commandCatalogue = make(map[string]commandSpec)
+
+func init() {
+	for _, spec := range commandList {
+		if spec.Params == nil {
+			spec.Params = []commandParam{}
+		}
+		commandCatalogue[spec.Name] = spec
+	}
+}
+
+// value converts a JSON decoded argument to the parameter type
+func (p commandParam) value(v interface{}) (interface{}, error) {
+	switch p.Type {
+	case paramString, paramText:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", p.Name)
+		}
+		if p.Type == paramText {
+			return text(s), nil
+		}
+		return s, nil
+	case paramBool:
+		switch b := v.(type) {
+		case bool:
+			if b {
+				return 1, nil
+			}
+			return 0, nil
+		case json.Number:
+			if b.String() == "0" || b.String() == "1" {
+				return b.String(), nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be a boolean", p.Name)
+	case paramInt, paramTimestamp:
+		if s, ok := v.(string); ok {
+			if p.Type == paramTimestamp && s == defaultNow {
+				return time.Now().Unix(), nil
+			}
+			v = json.Number(s)
+		}
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an integer", p.Name)
+		}
+		i, err := strconv.ParseInt(n.String(), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer", p.Name)
+		}
+		return i, nil
+	}
+	return nil, fmt.Errorf("%s has unsupported type %s", p.Name, p.Type)
+}
+
+// arguments validates input against the command parameters and returns the command
+// arguments in order. Omitted trailing optional parameters are left out.
+func (c commandSpec) arguments(input map[string]interface{}) ([]interface{}, error) {
+	for name := range input {
+		found := false
+		for _, p := range c.Params {
+			if p.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown parameter %s for %s", name, c.Name)
+		}
+	}
+
+	var args []interface{}
+	given := 0
+	for _, p := range c.Params {
+		v, ok := input[p.Name]
+		if !ok {
+			switch {
+			case p.Default != nil:
+				v = p.Default
+				if n, isInt := v.(int); isInt {
+					v = json.Number(strconv.Itoa(n))
+				}
+			case p.Optional:
+				args = append(args, "")
+				continue
+			default:
+				return nil, fmt.Errorf("missing parameter %s for %s", p.Name, c.Name)
+			}
+		}
+
+		value, err := p.value(v)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+		given = len(args)
+	}
+	return args[:given], nil
+}
+
+// unknownTarget returns an error naming the first target argument not defined in the objects.cache
+// of the snapshot r is served from
+func (a *Api) unknownTarget(r *http.Request, c commandSpec, args []interface{}) error {
+	snap := a.snapshotOf(r)
+
+	var host, hostgroup string
+	for i, arg := range args {
+		p := c.Params[i]
+		name, _ := arg.(string)
+		if p.Target == "" || name == "" {
+			continue
+		}
+
+		switch p.Target {
+		case targetHost:
+			host = name
+			if !snap.static.hasHost(name) {
+				return fmt.Errorf("Unknown host %s", name)
+			}
+		case targetService:
+			// Hostgroup commands may name a service without a host, meaning it on every member
+			if host == "" && hostgroup != "" {
+				if len(snap.static.hostgroupServiceMembers(hostgroup, name)) == 0 {
+					return fmt.Errorf("Unknown service %s in hostgroup %s", name, hostgroup)
+				}
+				continue
+			}
+			if !snap.static.hasService(host, name) {
+				return fmt.Errorf("Unknown service %s on host %s", name, host)
+			}
+		case targetHostgroup:
+			hostgroup = name
+			if !snap.static.hasHostgroup(name) {
+				return fmt.Errorf("Unknown hostgroup %s", name)
+			}
+		case targetServicegroup:
+			if !snap.static.hasServicegroup(name) {
+				return fmt.Errorf("Unknown servicegroup %s", name)
+			}
+		case targetContact:
+			if !snap.static.hasContact(name) {
+				return fmt.Errorf("Unknown contact %s", name)
+			}
+		case targetContactgroup:
+			if !snap.static.hasContactgroup(name) {
+				return fmt.Errorf("Unknown contactgroup %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+// prepareCommand validates a catalogue command and its parameters, and that the caller of r is
+// authorized for it, and returns the command line to write along with its arguments. On failure
+// it also returns the HTTP status code describing the error.
+func (a *Api) prepareCommand(r *http.Request, name string, input map[string]interface{}) (string, []interface{}, int, error) {
+	spec, ok := commandCatalogue[name]
+	if !ok {
+		return "", nil, http.StatusNotFound, fmt.Errorf("Unknown command %s", name)
+	}
+
+	args, err := spec.arguments(input)
+	if err != nil {
+		return "", nil, http.StatusBadRequest, err
+	}
+
+	if err := a.unknownTarget(r, spec, args); err != nil {
+		return "", nil, http.StatusNotFound, err
+	}
+
+	command, err := buildCommand(spec.Name, args...)
+	if err != nil {
+		return "", nil, http.StatusBadRequest, err
+	}
+
+	if err := a.authorizeCommands(r, []string{command}); err != nil {
+		// Denied commands are audited like those denied by submitCommands
+		a.audit(r, []string{command}, fmt.Sprintf("error: %s", err))
+		return "", nil, http.StatusForbidden, err
+	}
+	return command, args, http.StatusOK, nil
+}
+
+// HandleGetCommands lists the supported external commands with their parameters
+// GET: /commands
+func (a *Api) HandleGetCommands(w http.ResponseWriter, r *http.Request) {
+	var commands []commandSpec
+	for _, spec := range commandCatalogue {
+		commands = append(commands, spec)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(commands)
+}
+
+// HandleExecuteCommand executes any external command from the catalogue. With confirm=true
+// it replies 202 and the command can be followed at /commands/<id> until status.dat shows its effect.
+// POST: /commands/<COMMAND_NAME>[?confirm=true&timeout=<seconds>]
+//       {<param name>: <value>, ...}
+func (a *Api) HandleExecuteCommand(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if _, ok := commandCatalogue[vars["command"]]; !ok {
+		http.Error(w, fmt.Sprintf("Error: Unknown command %s", vars["command"]), http.StatusNotFound)
+		return
+	}
+
+	input := map[string]interface{}{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&input); err != nil && err != io.EOF {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	command, args, code, err := a.prepareCommand(r, vars["command"], input)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), code)
+		return
+	}
+
+	if r.URL.Query().Get("confirm") == "true" && !a.dryRun(r) {
+		a.executeConfirmedCommand(w, r, commandCatalogue[vars["command"]], command, args)
+		return
+	}
+
+	a.submitCommand(w, r, command)
+}
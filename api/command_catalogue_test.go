@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+)
+
+func TestExecuteCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		code    int
+		command string
+		// prefix is set for commands stamped with the current time
+		prefix bool
+	}{
+		{name: "no parameters", path: "/commands/ENABLE_NOTIFICATIONS", code: 200, command: "ENABLE_NOTIFICATIONS"},
+		{name: "service target", path: "/commands/DISABLE_SVC_CHECK", body: `{"host_name": "web01", "service_description": "HTTP"}`, code: 200, command: "DISABLE_SVC_CHECK;web01;HTTP"},
+		{name: "defaults", path: "/commands/ACKNOWLEDGE_HOST_PROBLEM", body: `{"host_name": "web01", "author": "jason", "comment": "on it; really"}`, code: 200, command: "ACKNOWLEDGE_HOST_PROBLEM;web01;2;1;1;jason;on it; really"},
+		{name: "explicit values", path: "/commands/ACKNOWLEDGE_HOST_PROBLEM", body: `{"host_name": "web01", "sticky": "0", "notify": false, "persistent": 0, "author": "jason", "comment": "on it"}`, code: 200, command: "ACKNOWLEDGE_HOST_PROBLEM;web01;0;0;0;jason;on it"},
+		{name: "timestamp default", path: "/commands/SCHEDULE_HOST_CHECK", body: `{"host_name": "web01"}`, code: 200, command: "SCHEDULE_HOST_CHECK;web01;", prefix: true},
+		{name: "trailing optional parameters", path: "/commands/DEL_DOWNTIME_BY_HOST_NAME", body: `{"host_name": "web01"}`, code: 200, command: "DEL_DOWNTIME_BY_HOST_NAME;web01"},
+		{name: "optional parameter gap", path: "/commands/DEL_DOWNTIME_BY_HOST_NAME", body: `{"host_name": "web01", "comment": "maintenance"}`, code: 200, command: "DEL_DOWNTIME_BY_HOST_NAME;web01;;;maintenance"},
+		{name: "expiring acknowledgement", path: "/commands/ACKNOWLEDGE_SVC_PROBLEM_EXPIRE", body: `{"host_name": "web01", "service_description": "HTTP", "expire_time": 1700000000, "author": "jason", "comment": "until the fix ships"}`, code: 200, command: "ACKNOWLEDGE_SVC_PROBLEM_EXPIRE;web01;HTTP;2;1;1;1700000000;jason;until the fix ships"},
+		{name: "clear flapping state", path: "/commands/CLEAR_HOST_FLAPPING_STATE", body: `{"host_name": "db01"}`, code: 200, command: "CLEAR_HOST_FLAPPING_STATE;db01"},
+		{name: "hostgroup service", path: "/commands/DEL_DOWNTIME_BY_HOSTGROUP_NAME", body: `{"hostgroup_name": "db-servers", "service_description": "MySQL"}`, code: 200, command: "DEL_DOWNTIME_BY_HOSTGROUP_NAME;db-servers;;MySQL"},
+		{name: "downtime", path: "/commands/SCHEDULE_SERVICEGROUP_HOST_DOWNTIME", body: `{"servicegroup_name": "web-checks", "start_time": 100, "end_time": 200, "duration": 100, "author": "jason", "comment": "upgrade"}`, code: 200, command: "SCHEDULE_SERVICEGROUP_HOST_DOWNTIME;web-checks;100;200;1;0;100;jason;upgrade"},
+		{name: "contact target", path: "/commands/DISABLE_CONTACT_HOST_NOTIFICATIONS", body: `{"contact_name": "jason"}`, code: 200, command: "DISABLE_CONTACT_HOST_NOTIFICATIONS;jason"},
+		{name: "contactgroup target", path: "/commands/ENABLE_CONTACTGROUP_SVC_NOTIFICATIONS", body: `{"contactgroup_name": "admins"}`, code: 200, command: "ENABLE_CONTACTGROUP_SVC_NOTIFICATIONS;admins"},
+
+		{name: "unknown command", path: "/commands/DO_SOMETHING", code: 404},
+		{name: "excluded command", path: "/commands/PROCESS_FILE", body: `{"file_name": "/etc/passwd", "delete": 1}`, code: 404},
+		{name: "unknown parameter", path: "/commands/ENABLE_HOST_CHECK", body: `{"host_name": "web01", "hostname": "web01"}`, code: 400},
+		{name: "missing parameter", path: "/commands/ENABLE_HOST_CHECK", code: 400},
+		{name: "invalid integer", path: "/commands/DEL_HOST_COMMENT", body: `{"comment_id": "4;SHUTDOWN_PROGRAM"}`, code: 400},
+		{name: "invalid boolean", path: "/commands/ADD_HOST_COMMENT", body: `{"host_name": "web01", "persistent": 2, "author": "jason", "comment": "x"}`, code: 400},
+		{name: "invalid body", path: "/commands/ENABLE_HOST_CHECK", body: `["web01"]`, code: 400},
+		{name: "newline in text", path: "/commands/ADD_HOST_COMMENT", body: `{"host_name": "web01", "author": "jason", "comment": "x\n[0] SHUTDOWN_PROGRAM"}`, code: 400},
+		{name: "unknown host", path: "/commands/ENABLE_HOST_CHECK", body: `{"host_name": "nope"}`, code: 404},
+		{name: "unknown service", path: "/commands/ENABLE_SVC_CHECK", body: `{"host_name": "db01", "service_description": "HTTP"}`, code: 404},
+		{name: "unknown hostgroup service", path: "/commands/DEL_DOWNTIME_BY_HOSTGROUP_NAME", body: `{"hostgroup_name": "web-servers", "service_description": "MySQL"}`, code: 404},
+		{name: "unknown hostgroup", path: "/commands/ENABLE_HOSTGROUP_HOST_CHECKS", body: `{"hostgroup_name": "nope"}`, code: 404},
+		{name: "unknown servicegroup", path: "/commands/ENABLE_SERVICEGROUP_SVC_CHECKS", body: `{"servicegroup_name": "nope"}`, code: 404},
+		{name: "unknown contact", path: "/commands/ENABLE_CONTACT_SVC_NOTIFICATIONS", body: `{"contact_name": "nope"}`, code: 404},
+		{name: "unknown contactgroup", path: "/commands/ENABLE_CONTACTGROUP_HOST_NOTIFICATIONS", body: `{"contactgroup_name": "nope"}`, code: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			api := newTestApi(t)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			api.router.ServeHTTP(w, r)
+
+			is.Equal(w.Code, tt.code)
+			commands := writtenCommands(t, api)
+			if tt.command == "" {
+				is.Equal(len(commands), 0)
+				return
+			}
+			is.Equal(len(commands), 1)
+			if tt.prefix {
+				is.True(strings.HasPrefix(commands[0], tt.command))
+			} else {
+				is.Equal(commands[0], tt.command)
+			}
+		})
+	}
+}
+
+func TestGetCommands(t *testing.T) {
+	is := is.New(t)
+	api := newTestApi(t)
+
+	w := httptest.NewRecorder()
+	api.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/commands", nil))
+	is.Equal(w.Code, 200)
+
+	var commands []commandSpec
+	is.NoErr(json.NewDecoder(w.Body).Decode(&commands))
+	is.Equal(len(commands), len(commandList))
+	is.Equal(commands[0].Name, "ACKNOWLEDGE_HOST_PROBLEM")
+	is.Equal(len(commands[0].Params), 6)
+	is.Equal(commands[0].Params[1].Default, float64(2))
+}
@@ -0,0 +1,434 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Confirmation states of a submitted command
+const (
+	confirmPending   = "pending"
+	confirmConfirmed = "confirmed"
+	confirmTimedOut  = "timed_out"
+)
+
+const (
+	defaultConfirmTimeout = 5 * time.Minute
+	maxConfirmTimeout     = time.Hour
+	// confirmRetention is how long a confirmed or timed out command can still be looked up
+	confirmRetention = time.Hour
+)
+
+// expectation reports whether status data shows the effect of a command
+type expectation func(data *StatusData) bool
+
+type trackedCommand struct {
+	ID        string `json:"id"`
+	Command   string `json:"command"`
+	Status    string `json:"status"`
+	Submitted int64  `json:"submitted"`
+	Deadline  int64  `json:"deadline"`
+	Resolved  int64  `json:"resolved,omitempty"`
+
+	expect expectation
+}
+
+// commandTracker follows submitted commands until a status refresh shows their effect
+type commandTracker struct {
+	mutex    sync.Mutex
+	commands map[string]*trackedCommand
+}
+
+func newCommandTracker() *commandTracker {
+	return &commandTracker{commands: make(map[string]*trackedCommand)}
+}
+
+// add starts tracking a command submitted now and returns a copy of its state
+func (t *commandTracker) add(command string, expect expectation, timeout time.Duration) (trackedCommand, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return trackedCommand{}, err
+	}
+
+	now := time.Now()
+	c := &trackedCommand{
+		ID:        hex.EncodeToString(id),
+		Command:   command,
+		Status:    confirmPending,
+		Submitted: now.Unix(),
+		Deadline:  now.Add(timeout).Unix(),
+		expect:    expect,
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.commands[c.ID] = c
+	return *c, nil
+}
+
+// get returns a copy of the state of a tracked command
+func (t *commandTracker) get(id string) (trackedCommand, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	c, ok := t.commands[id]
+	if !ok {
+		return trackedCommand{}, false
+	}
+	c.timeout(time.Now())
+	return *c, true
+}
+
+// observe checks pending commands against freshly read status data. Only status data written
+// by Nagios after the second a command was submitted in can confirm it.
+func (t *commandTracker) observe(data *StatusData) {
+	var created int64
+	if data.Info != nil {
+		created, _ = strconv.ParseInt(data.Info.Created, 10, 64)
+	}
+
+	now := time.Now()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for id, c := range t.commands {
+		if c.Status == confirmPending && created > c.Submitted && c.expect(data) {
+			c.Status = confirmConfirmed
+			c.Resolved = now.Unix()
+		}
+		c.timeout(now)
+
+		if c.Status != confirmPending && now.Sub(time.Unix(c.Resolved, 0)) > confirmRetention {
+			delete(t.commands, id)
+		}
+	}
+}
+
+// timeout marks a pending command past its deadline as timed out
+func (c *trackedCommand) timeout(now time.Time) {
+	if c.Status == confirmPending && now.Unix() > c.Deadline {
+		c.Status = confirmTimedOut
+		c.Resolved = now.Unix()
+	}
+}
+
+// Commands toggling a program wide flag, with the status.dat field they set
+var programToggles = []struct{ on, off, field string }{
+	{"ENABLE_NOTIFICATIONS", "DISABLE_NOTIFICATIONS", "enable_notifications"},
+	{"START_EXECUTING_HOST_CHECKS", "STOP_EXECUTING_HOST_CHECKS", "active_host_checks_enabled"},
+	{"START_EXECUTING_SVC_CHECKS", "STOP_EXECUTING_SVC_CHECKS", "active_service_checks_enabled"},
+	{"START_ACCEPTING_PASSIVE_HOST_CHECKS", "STOP_ACCEPTING_PASSIVE_HOST_CHECKS", "passive_host_checks_enabled"},
+	{"START_ACCEPTING_PASSIVE_SVC_CHECKS", "STOP_ACCEPTING_PASSIVE_SVC_CHECKS", "passive_service_checks_enabled"},
+	{"ENABLE_EVENT_HANDLERS", "DISABLE_EVENT_HANDLERS", "enable_event_handlers"},
+	{"ENABLE_FLAP_DETECTION", "DISABLE_FLAP_DETECTION", "enable_flap_detection"},
+	{"ENABLE_PERFORMANCE_DATA", "DISABLE_PERFORMANCE_DATA", "process_performance_data"},
+	{"START_OBSESSING_OVER_HOST_CHECKS", "STOP_OBSESSING_OVER_HOST_CHECKS", "obsess_over_hosts"},
+	{"START_OBSESSING_OVER_SVC_CHECKS", "STOP_OBSESSING_OVER_SVC_CHECKS", "obsess_over_services"},
+	{"ENABLE_HOST_FRESHNESS_CHECKS", "DISABLE_HOST_FRESHNESS_CHECKS", "check_host_freshness"},
+	{"ENABLE_SERVICE_FRESHNESS_CHECKS", "DISABLE_SERVICE_FRESHNESS_CHECKS", "check_service_freshness"},
+}
+
+// Commands toggling a flag of one host or service, with the status.dat field they set
+var objectToggles = []struct{ on, off, field string }{
+	{"ENABLE_HOST_CHECK", "DISABLE_HOST_CHECK", "active_checks_enabled"},
+	{"ENABLE_SVC_CHECK", "DISABLE_SVC_CHECK", "active_checks_enabled"},
+	{"ENABLE_HOST_NOTIFICATIONS", "DISABLE_HOST_NOTIFICATIONS", "notifications_enabled"},
+	{"ENABLE_SVC_NOTIFICATIONS", "DISABLE_SVC_NOTIFICATIONS", "notifications_enabled"},
+	{"ENABLE_PASSIVE_HOST_CHECKS", "DISABLE_PASSIVE_HOST_CHECKS", "passive_checks_enabled"},
+	{"ENABLE_PASSIVE_SVC_CHECKS", "DISABLE_PASSIVE_SVC_CHECKS", "passive_checks_enabled"},
+	{"ENABLE_HOST_EVENT_HANDLER", "DISABLE_HOST_EVENT_HANDLER", "event_handler_enabled"},
+	{"ENABLE_SVC_EVENT_HANDLER", "DISABLE_SVC_EVENT_HANDLER", "event_handler_enabled"},
+	{"ENABLE_HOST_FLAP_DETECTION", "DISABLE_HOST_FLAP_DETECTION", "flap_detection_enabled"},
+	{"ENABLE_SVC_FLAP_DETECTION", "DISABLE_SVC_FLAP_DETECTION", "flap_detection_enabled"},
+	{"START_OBSESSING_OVER_HOST", "STOP_OBSESSING_OVER_HOST", "obsess"},
+	{"START_OBSESSING_OVER_SVC", "STOP_OBSESSING_OVER_SVC", "obsess"},
+	{"ACKNOWLEDGE_HOST_PROBLEM", "REMOVE_HOST_ACKNOWLEDGEMENT", "problem_has_been_acknowledged"},
+	{"ACKNOWLEDGE_SVC_PROBLEM", "REMOVE_SVC_ACKNOWLEDGEMENT", "problem_has_been_acknowledged"},
+	{"ACKNOWLEDGE_HOST_PROBLEM_EXPIRE", "REMOVE_HOST_ACKNOWLEDGEMENT", "problem_has_been_acknowledged"},
+	{"ACKNOWLEDGE_SVC_PROBLEM_EXPIRE", "REMOVE_SVC_ACKNOWLEDGEMENT", "problem_has_been_acknowledged"},
+}
+
+func findHostStatus(data *StatusData, host string) *HostStatus {
+	for _, h := range data.Hosts {
+		if h.HostName == host {
+			return h
+		}
+	}
+	return nil
+}
+
+func findServiceStatus(data *StatusData, host, service string) *ServiceStatus {
+	for _, s := range data.Services {
+		if s.HostName == host && s.ServiceDescription == service {
+			return s
+		}
+	}
+	return nil
+}
+
+// atLeast reports whether the numeric status field value is at least min
+func atLeast(value string, min int64) bool {
+	i, err := strconv.ParseInt(value, 10, 64)
+	return err == nil && i >= min
+}
+
+// expectationFor returns the effect of a catalogue command on status data, or nil when
+// the command has no effect the API knows how to observe
+func expectationFor(spec commandSpec, args []interface{}, submitted int64) expectation {
+	values := make(map[string]string)
+	for i, arg := range args {
+		values[spec.Params[i].Name] = fmt.Sprint(arg)
+	}
+	host, service := values["host_name"], values["service_description"]
+	_, isService := values["service_description"]
+
+	// field returns the status field of the command target, or "" when it is not in status data
+	field := func(data *StatusData, name string) string {
+		var obj interface{}
+		if isService {
+			if s := findServiceStatus(data, host, service); s != nil {
+				obj = s
+			}
+		} else if h := findHostStatus(data, host); h != nil {
+			obj = h
+		}
+		if obj == nil {
+			return ""
+		}
+		value, _ := getField(obj, name)
+		return value
+	}
+
+	for _, toggle := range programToggles {
+		if spec.Name != toggle.on && spec.Name != toggle.off {
+			continue
+		}
+		want := "0"
+		if spec.Name == toggle.on {
+			want = "1"
+		}
+		name := toggle.field
+		return func(data *StatusData) bool {
+			if data.Program == nil {
+				return false
+			}
+			value, _ := getField(data.Program, name)
+			return value == want
+		}
+	}
+
+	for _, toggle := range objectToggles {
+		if spec.Name != toggle.on && spec.Name != toggle.off {
+			continue
+		}
+		want := "0"
+		if spec.Name == toggle.on {
+			want = "1"
+		}
+		name := toggle.field
+		return func(data *StatusData) bool { return field(data, name) == want }
+	}
+
+	author, comment := values["author"], values["comment"]
+	switch spec.Name {
+	case "ADD_HOST_COMMENT":
+		return func(data *StatusData) bool {
+			for _, c := range data.HostComments {
+				if c.HostName == host && c.Author == author && c.CommentData == comment && atLeast(c.EntryTime, submitted) {
+					return true
+				}
+			}
+			return false
+		}
+	case "ADD_SVC_COMMENT":
+		return func(data *StatusData) bool {
+			for _, c := range data.ServiceComments {
+				if c.HostName == host && c.ServiceDescription == service && c.Author == author && c.CommentData == comment && atLeast(c.EntryTime, submitted) {
+					return true
+				}
+			}
+			return false
+		}
+	case "SCHEDULE_HOST_DOWNTIME":
+		return func(data *StatusData) bool {
+			for _, d := range data.HostDowntimes {
+				if d.HostName == host && d.Author == author && d.Comment == comment && atLeast(d.EntryTime, submitted) {
+					return true
+				}
+			}
+			return false
+		}
+	case "SCHEDULE_SVC_DOWNTIME":
+		return func(data *StatusData) bool {
+			for _, d := range data.ServiceDowntimes {
+				if d.HostName == host && d.ServiceDescription == service && d.Author == author && d.Comment == comment && atLeast(d.EntryTime, submitted) {
+					return true
+				}
+			}
+			return false
+		}
+	case "DEL_HOST_COMMENT":
+		id := values["comment_id"]
+		return func(data *StatusData) bool {
+			for _, c := range data.HostComments {
+				if c.CommentID == id {
+					return false
+				}
+			}
+			return true
+		}
+	case "DEL_SVC_COMMENT":
+		id := values["comment_id"]
+		return func(data *StatusData) bool {
+			for _, c := range data.ServiceComments {
+				if c.CommentID == id {
+					return false
+				}
+			}
+			return true
+		}
+	case "DEL_HOST_DOWNTIME":
+		id := values["downtime_id"]
+		return func(data *StatusData) bool {
+			for _, d := range data.HostDowntimes {
+				if d.DowntimeID == id {
+					return false
+				}
+			}
+			return true
+		}
+	case "DEL_SVC_DOWNTIME":
+		id := values["downtime_id"]
+		return func(data *StatusData) bool {
+			for _, d := range data.ServiceDowntimes {
+				if d.DowntimeID == id {
+					return false
+				}
+			}
+			return true
+		}
+	case "SCHEDULE_HOST_CHECK", "SCHEDULE_FORCED_HOST_CHECK", "SCHEDULE_SVC_CHECK", "SCHEDULE_FORCED_SVC_CHECK":
+		checkTime, _ := strconv.ParseInt(values["check_time"], 10, 64)
+		return func(data *StatusData) bool { return atLeast(field(data, "last_check"), checkTime) }
+	case "PROCESS_HOST_CHECK_RESULT", "PROCESS_SERVICE_CHECK_RESULT":
+		return func(data *StatusData) bool { return atLeast(field(data, "last_check"), submitted) }
+	}
+	return nil
+}
+
+// unknownDeletion returns an error when a command deletes a comment or downtime by an ID that
+// is not in data. Its absence would otherwise confirm the command on the next refresh.
+func unknownDeletion(spec commandSpec, args []interface{}, data *StatusData) error {
+	if len(args) == 0 {
+		return nil
+	}
+	id := fmt.Sprint(args[0])
+
+	var kind string
+	var ids []string
+	switch spec.Name {
+	case "DEL_HOST_COMMENT":
+		kind = "host comment"
+		for _, c := range data.HostComments {
+			ids = append(ids, c.CommentID)
+		}
+	case "DEL_SVC_COMMENT":
+		kind = "service comment"
+		for _, c := range data.ServiceComments {
+			ids = append(ids, c.CommentID)
+		}
+	case "DEL_HOST_DOWNTIME":
+		kind = "host downtime"
+		for _, d := range data.HostDowntimes {
+			ids = append(ids, d.DowntimeID)
+		}
+	case "DEL_SVC_DOWNTIME":
+		kind = "service downtime"
+		for _, d := range data.ServiceDowntimes {
+			ids = append(ids, d.DowntimeID)
+		}
+	default:
+		return nil
+	}
+
+	if !stringInSlice(id, ids) {
+		return fmt.Errorf("Unknown %s %s", kind, id)
+	}
+	return nil
+}
+
+// confirmTimeout reads the optional timeout query parameter, in seconds
+func confirmTimeout(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get("timeout")
+	if value == "" {
+		return defaultConfirmTimeout, nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 || time.Duration(seconds)*time.Second > maxConfirmTimeout {
+		return 0, fmt.Errorf("timeout must be between 1 and %d seconds", int(maxConfirmTimeout.Seconds()))
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// executeConfirmedCommand writes a catalogue command and replies 202 with the ID under which
+// its confirmation can be followed
+func (a *Api) executeConfirmedCommand(w http.ResponseWriter, r *http.Request, spec commandSpec, command string, args []interface{}) {
+	timeout, err := confirmTimeout(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	expect := expectationFor(spec, args, time.Now().Unix())
+	if expect == nil {
+		http.Error(w, fmt.Sprintf("Error: confirmation is not supported for %s", spec.Name), http.StatusBadRequest)
+		return
+	}
+	if err := unknownDeletion(spec, args, a.snapshotOf(r).status); err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusNotFound)
+		return
+	}
+
+	if _, err := a.submitCommands(r, []string{command}); err != nil {
+		replyCommandError(w, err)
+		return
+	}
+
+	tracked, err := a.confirmations.add(command, expect, timeout)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: command written but can not be tracked: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/commands/"+tracked.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(tracked)
+}
+
+// HandleGetCommandConfirmation reports whether a command submitted with confirm=true was
+// confirmed by status data, is still pending or timed out. Only callers authorized to issue the
+// command can follow it.
+// GET: /commands/<id>
+func (a *Api) HandleGetCommandConfirmation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tracked, ok := a.confirmations.get(vars["id"])
+	if ok && a.authorizeCommands(r, []string{tracked.Command}) != nil {
+		ok = false
+	}
+	if !ok {
+		http.Error(w, fmt.Sprintf("Error: Unknown command id %s", vars["id"]), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tracked)
+}
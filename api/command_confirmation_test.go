@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+)
+
+func TestCommandConfirmation(t *testing.T) {
+	later := strconv.FormatInt(time.Now().Unix()+2, 10)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		// before is the status data the command is submitted against
+		before *StatusData
+		status *StatusData
+		result string
+	}{
+		{
+			name:   "program flag",
+			path:   "/commands/DISABLE_NOTIFICATIONS?confirm=true",
+			status: &StatusData{Info: &InfoStatus{Created: later}, Program: &ProgramStatus{EnableNotifications: "0"}},
+			result: confirmConfirmed,
+		},
+		{
+			name:   "program flag unchanged",
+			path:   "/commands/DISABLE_NOTIFICATIONS?confirm=true",
+			status: &StatusData{Info: &InfoStatus{Created: later}, Program: &ProgramStatus{EnableNotifications: "1"}},
+			result: confirmPending,
+		},
+		{
+			name:   "status written before the command",
+			path:   "/commands/DISABLE_NOTIFICATIONS?confirm=true",
+			status: &StatusData{Info: &InfoStatus{Created: "1484082900"}, Program: &ProgramStatus{EnableNotifications: "0"}},
+			result: confirmPending,
+		},
+		{
+			name:   "service flag",
+			path:   "/commands/ENABLE_SVC_CHECK?confirm=true",
+			body:   `{"host_name": "web01", "service_description": "HTTP"}`,
+			status: &StatusData{Info: &InfoStatus{Created: later}, Services: []*ServiceStatus{{HostName: "web01", ServiceDescription: "HTTP", ActiveChecksEnabled: "1"}}},
+			result: confirmConfirmed,
+		},
+		{
+			name:   "new comment",
+			path:   "/commands/ADD_HOST_COMMENT?confirm=true&timeout=60",
+			body:   `{"host_name": "web01", "author": "jason", "comment": "rack down"}`,
+			status: &StatusData{Info: &InfoStatus{Created: later}, HostComments: []*HostComment{{HostName: "web01", Author: "jason", CommentData: "rack down", EntryTime: later}}},
+			result: confirmConfirmed,
+		},
+		{
+			name:   "deleted downtime",
+			path:   "/commands/DEL_SVC_DOWNTIME?confirm=true",
+			body:   `{"downtime_id": 2}`,
+			before: &StatusData{ServiceDowntimes: []*ServiceDowntime{{DowntimeID: "2"}, {DowntimeID: "3"}}},
+			status: &StatusData{Info: &InfoStatus{Created: later}, ServiceDowntimes: []*ServiceDowntime{{DowntimeID: "3"}}},
+			result: confirmConfirmed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			api := newTestApi(t)
+			if tt.before != nil {
+				api.publishStatus(tt.before, time.Time{})
+			}
+
+			w := httptest.NewRecorder()
+			api.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			is.Equal(w.Code, http.StatusAccepted)
+			is.Equal(len(writtenCommands(t, api)), 1)
+
+			var tracked trackedCommand
+			is.NoErr(json.NewDecoder(w.Body).Decode(&tracked))
+			is.Equal(tracked.Status, confirmPending)
+			is.Equal(w.Header().Get("Location"), "/commands/"+tracked.ID)
+
+			api.confirmations.observe(tt.status)
+
+			w = httptest.NewRecorder()
+			api.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/commands/"+tracked.ID, nil))
+			is.Equal(w.Code, 200)
+			is.NoErr(json.NewDecoder(w.Body).Decode(&tracked))
+			is.Equal(tracked.Status, tt.result)
+		})
+	}
+}
+
+func TestCommandConfirmationTimeout(t *testing.T) {
+	is := is.New(t)
+	api := newTestApi(t)
+
+	w := httptest.NewRecorder()
+	api.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/commands/ENABLE_HOST_CHECK?confirm=true&timeout=1", strings.NewReader(`{"host_name": "web01"}`)))
+	is.Equal(w.Code, http.StatusAccepted)
+
+	var tracked trackedCommand
+	is.NoErr(json.NewDecoder(w.Body).Decode(&tracked))
+	api.confirmations.commands[tracked.ID].Deadline = time.Now().Unix() - 1
+
+	w = httptest.NewRecorder()
+	api.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/commands/"+tracked.ID, nil))
+	is.NoErr(json.NewDecoder(w.Body).Decode(&tracked))
+	is.Equal(tracked.Status, confirmTimedOut)
+}
+
+func TestCommandConfirmationErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{name: "unsupported command", method: http.MethodPost, path: "/commands/SAVE_STATE_INFORMATION?confirm=true", code: 400},
+		{name: "invalid timeout", method: http.MethodPost, path: "/commands/ENABLE_NOTIFICATIONS?confirm=true&timeout=0", code: 400},
+		{name: "timeout too long", method: http.MethodPost, path: "/commands/ENABLE_NOTIFICATIONS?confirm=true&timeout=86400", code: 400},
+		{name: "unknown downtime", method: http.MethodPost, path: "/commands/DEL_SVC_DOWNTIME?confirm=true", body: `{"downtime_id": 2}`, code: 404},
+		{name: "unknown comment", method: http.MethodPost, path: "/commands/DEL_HOST_COMMENT?confirm=true", body: `{"comment_id": 7}`, code: 404},
+		{name: "unknown id", method: http.MethodGet, path: "/commands/0123456789abcdef", code: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			api := newTestApi(t)
+
+			w := httptest.NewRecorder()
+			api.router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			is.Equal(w.Code, tt.code)
+			is.Equal(len(writtenCommands(t, api)), 0)
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -50,8 +52,11 @@ func (a *Api) HandleAcknowledgeHostProblem(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s;%d;%d;%d;%s;%s", "ACKNOWLEDGE_HOST_PROBLEM", data.Hostname, data.Sticky, data.Notify, data.Persistent, data.Author, data.Comment)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHost(w, r, data.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "ACKNOWLEDGE_HOST_PROBLEM", data.Hostname, data.Sticky, data.Notify, data.Persistent, data.Author, text(data.Comment))
 }
 
 // HandleAcknowledgeServiceProblem executes ACKNOWLEDGE_SVC_PROBLEM
@@ -96,8 +101,11 @@ func (a *Api) HandleAcknowledgeServiceProblem(w http.ResponseWriter, r *http.Req
 		data.Persistent = 1
 	}
 
-	command := fmt.Sprintf("%s;%s;%s;%d;%d;%d;%s;%s", "ACKNOWLEDGE_SVC_PROBLEM", data.Hostname, data.ServiceDescription, data.Sticky, data.Notify, data.Persistent, data.Author, data.Comment)
-	a.WriteCommandToFile(w, command)
+	if !a.requireService(w, r, data.Hostname, data.ServiceDescription) {
+		return
+	}
+
+	a.executeCommand(w, r, "ACKNOWLEDGE_SVC_PROBLEM", data.Hostname, data.ServiceDescription, data.Sticky, data.Notify, data.Persistent, data.Author, text(data.Comment))
 }
 
 // HandleAddHostComment executes ADD_HOST_COMMENT
@@ -136,8 +144,11 @@ func (a *Api) HandleAddHostComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s;%d;%s;%s", "ADD_HOST_COMMENT", data.Hostname, data.Persistent, data.Author, data.Comment)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHost(w, r, data.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "ADD_HOST_COMMENT", data.Hostname, data.Persistent, data.Author, text(data.Comment))
 
 }
 
@@ -183,8 +194,11 @@ func (a *Api) HandleAddServiceComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s;%s;%d;%s;%s", "ADD_SVC_COMMENT", data.Hostname, data.Service, data.Persistent, data.Author, data.Comment)
-	a.WriteCommandToFile(w, command)
+	if !a.requireService(w, r, data.Hostname, data.Service) {
+		return
+	}
+
+	a.executeCommand(w, r, "ADD_SVC_COMMENT", data.Hostname, data.Service, data.Persistent, data.Author, text(data.Comment))
 }
 
 // HandleDeleteAllHostCommnet executes DEL_ALL_HOST_COMMENTS
@@ -204,8 +218,11 @@ func (a *Api) HandleDeleteAllHostCommnet(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "DEL_ALL_HOST_COMMENTS", data.Hostname)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHost(w, r, data.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "DEL_ALL_HOST_COMMENTS", data.Hostname)
 }
 
 // HandleDeleteAllServiceComment executes DEL_ALL_SVC_COMMENTS
@@ -225,8 +242,11 @@ func (a *Api) HandleDeleteAllServiceComment(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "DEL_ALL_SVC_COMMENTS", data.Hostname)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHost(w, r, data.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "DEL_ALL_SVC_COMMENTS", data.Hostname)
 }
 
 // HandleDeleteHostComment executes DEL_HOST_COMMENT
@@ -246,8 +266,7 @@ func (a *Api) HandleDeleteHostComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "DEL_HOST_COMMENT", data.CommentID)
-	a.WriteCommandToFile(w, command)
+	a.executeCommand(w, r, "DEL_HOST_COMMENT", data.CommentID)
 }
 
 // HandleDeleteServiceComment executes DEL_SVC_COMMENT
@@ -267,8 +286,90 @@ func (a *Api) HandleDeleteServiceComment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "DEL_SVC_COMMENT", data.CommentID)
-	a.WriteCommandToFile(w, command)
+	a.executeCommand(w, r, "DEL_SVC_COMMENT", data.CommentID)
+}
+
+// HandleDeleteHostDowntime executes DEL_HOST_DOWNTIME
+// DEL_HOST_DOWNTIME;<downtime_id>
+func (a *Api) HandleDeleteHostDowntime(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var data struct {
+		DowntimeID string
+	}
+	err := decoder.Decode(&data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), 400)
+		return
+	}
+
+	if data.DowntimeID == "" {
+		http.Error(w, fmt.Sprintf("Error: DowntimeID field is required"), 400)
+		return
+	}
+
+	a.executeCommand(w, r, "DEL_HOST_DOWNTIME", data.DowntimeID)
+}
+
+// HandleDeleteServiceDowntime executes DEL_SVC_DOWNTIME
+// DEL_SVC_DOWNTIME;<downtime_id>
+func (a *Api) HandleDeleteServiceDowntime(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var data struct {
+		DowntimeID string
+	}
+	err := decoder.Decode(&data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), 400)
+		return
+	}
+
+	if data.DowntimeID == "" {
+		http.Error(w, fmt.Sprintf("Error: DowntimeID field is required"), 400)
+		return
+	}
+
+	a.executeCommand(w, r, "DEL_SVC_DOWNTIME", data.DowntimeID)
+}
+
+// HandleDeleteDowntimeByHostName executes DEL_DOWNTIME_BY_HOST_NAME
+// DEL_DOWNTIME_BY_HOST_NAME;<host_name>[;<service_desc>[;<start_time>[;<comment>]]]
+func (a *Api) HandleDeleteDowntimeByHostName(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var data struct {
+		Hostname           string `json:"hostname"`
+		ServiceDescription string `json:"service_description"`
+		StartTime          int64  `json:"start_time"`
+		Comment            string `json:"comment"`
+	}
+	err := decoder.Decode(&data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), 400)
+		return
+	}
+
+	if data.Hostname == "" {
+		http.Error(w, fmt.Sprintf("Error: Hostname field is required"), 400)
+		return
+	}
+
+	if !a.requireHost(w, r, data.Hostname) {
+		return
+	}
+
+	if data.ServiceDescription != "" && !a.requireService(w, r, data.Hostname, data.ServiceDescription) {
+		return
+	}
+
+	// Optional arguments are positional: drop the trailing empty ones and keep earlier ones as placeholders
+	args := []interface{}{data.Hostname, data.ServiceDescription, "", data.Comment}
+	if data.StartTime != 0 {
+		args[2] = data.StartTime
+	}
+	for args[len(args)-1] == "" {
+		args = args[:len(args)-1]
+	}
+
+	a.executeCommand(w, r, "DEL_DOWNTIME_BY_HOST_NAME", args...)
 }
 
 // HandleDisableAllNotificationBeyondHost executes DISABLE_ALL_NOTIFICATIONS_BEYOND_HOST
@@ -288,8 +389,11 @@ func (a *Api) HandleDisableAllNotificationBeyondHost(w http.ResponseWriter, r *h
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "DISABLE_ALL_NOTIFICATIONS_BEYOND_HOST", data.Hostname)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHost(w, r, data.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "DISABLE_ALL_NOTIFICATIONS_BEYOND_HOST", data.Hostname)
 }
 
 // HandleEnableAllNotificationBeyondHost executes ENABLE_ALL_NOTIFICATIONS_BEYOND_HOST
@@ -309,8 +413,11 @@ func (a *Api) HandleEnableAllNotificationBeyondHost(w http.ResponseWriter, r *ht
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "ENABLE_ALL_NOTIFICATIONS_BEYOND_HOST", data.Hostname)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHost(w, r, data.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "ENABLE_ALL_NOTIFICATIONS_BEYOND_HOST", data.Hostname)
 }
 
 // HandleDisableHostgroupHostChecks executes DISABLE_HOSTGROUP_HOST_CHECKS
@@ -330,8 +437,11 @@ func (a *Api) HandleDisableHostgroupHostChecks(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "DISABLE_HOSTGROUP_HOST_CHECKS", data.Hostgroup)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHostgroup(w, r, data.Hostgroup) {
+		return
+	}
+
+	a.executeCommand(w, r, "DISABLE_HOSTGROUP_HOST_CHECKS", data.Hostgroup)
 }
 
 // HandleEnableHostgroupHostChecks executes ENABLE_HOSTGROUP_HOST_CHECKS
@@ -351,8 +461,11 @@ func (a *Api) HandleEnableHostgroupHostChecks(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "ENABLE_HOSTGROUP_HOST_CHECKS", data.Hostgroup)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHostgroup(w, r, data.Hostgroup) {
+		return
+	}
+
+	a.executeCommand(w, r, "ENABLE_HOSTGROUP_HOST_CHECKS", data.Hostgroup)
 }
 
 // HandleDisableHostgroupHostNotification executes DISABLE_HOSTGROUP_HOST_NOTIFICATIONS
@@ -372,8 +485,11 @@ func (a *Api) HandleDisableHostgroupHostNotification(w http.ResponseWriter, r *h
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "DISABLE_HOSTGROUP_HOST_NOTIFICATIONS", data.Hostgroup)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHostgroup(w, r, data.Hostgroup) {
+		return
+	}
+
+	a.executeCommand(w, r, "DISABLE_HOSTGROUP_HOST_NOTIFICATIONS", data.Hostgroup)
 }
 
 // HandleEnableHostgroupHostNotification executes ENABLE_HOSTGROUP_HOST_NOTIFICATIONS;<hostgroup_name>
@@ -393,8 +509,11 @@ func (a *Api) HandleEnableHostgroupHostNotification(w http.ResponseWriter, r *ht
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "ENABLE_HOSTGROUP_HOST_NOTIFICATIONS", data.Hostgroup)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHostgroup(w, r, data.Hostgroup) {
+		return
+	}
+
+	a.executeCommand(w, r, "ENABLE_HOSTGROUP_HOST_NOTIFICATIONS", data.Hostgroup)
 }
 
 // HandleDisableHostgroupServiceChecks executes DISABLE_HOSTGROUP_SVC_CHECKS
@@ -414,8 +533,11 @@ func (a *Api) HandleDisableHostgroupServiceChecks(w http.ResponseWriter, r *http
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "DISABLE_HOSTGROUP_SVC_CHECKS", data.Hostgroup)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHostgroup(w, r, data.Hostgroup) {
+		return
+	}
+
+	a.executeCommand(w, r, "DISABLE_HOSTGROUP_SVC_CHECKS", data.Hostgroup)
 }
 
 // HandleEnableHostgroupServiceChecks executes ENABLE_HOSTGROUP_SVC_CHECKS
@@ -435,8 +557,11 @@ func (a *Api) HandleEnableHostgroupServiceChecks(w http.ResponseWriter, r *http.
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "ENABLE_HOSTGROUP_SVC_CHECKS", data.Hostgroup)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHostgroup(w, r, data.Hostgroup) {
+		return
+	}
+
+	a.executeCommand(w, r, "ENABLE_HOSTGROUP_SVC_CHECKS", data.Hostgroup)
 }
 
 // HandleDisableHostgroupServiceNotifications executes DISABLE_HOSTGROUP_SVC_NOTIFICATIONS
@@ -456,8 +581,11 @@ func (a *Api) HandleDisableHostgroupServiceNotifications(w http.ResponseWriter,
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "DISABLE_HOSTGROUP_SVC_NOTIFICATIONS", data.Hostgroup)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHostgroup(w, r, data.Hostgroup) {
+		return
+	}
+
+	a.executeCommand(w, r, "DISABLE_HOSTGROUP_SVC_NOTIFICATIONS", data.Hostgroup)
 }
 
 // HandleEnableHostgroupServiceNotifications executes ENABLE_HOSTGROUP_SVC_NOTIFICATIONS
@@ -477,8 +605,11 @@ func (a *Api) HandleEnableHostgroupServiceNotifications(w http.ResponseWriter, r
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "ENABLE_HOSTGROUP_SVC_NOTIFICATIONS", data.Hostgroup)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHostgroup(w, r, data.Hostgroup) {
+		return
+	}
+
+	a.executeCommand(w, r, "ENABLE_HOSTGROUP_SVC_NOTIFICATIONS", data.Hostgroup)
 }
 
 // HandleDisableHostandChildNotifications executes DISABLE_HOST_AND_CHILD_NOTIFICATIONS
@@ -498,8 +629,11 @@ func (a *Api) HandleDisableHostandChildNotifications(w http.ResponseWriter, r *h
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "DISABLE_HOST_AND_CHILD_NOTIFICATIONS", data.Hostname)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHost(w, r, data.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "DISABLE_HOST_AND_CHILD_NOTIFICATIONS", data.Hostname)
 }
 
 // HandleEnableHostandChildNotifications executes ENABLE_HOST_AND_CHILD_NOTIFICATIONS
@@ -519,8 +653,11 @@ func (a *Api) HandleEnableHostandChildNotifications(w http.ResponseWriter, r *ht
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "ENABLE_HOST_AND_CHILD_NOTIFICATIONS", data.Hostname)
-	a.WriteCommandToFile(w, command)
+	if !a.requireHost(w, r, data.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "ENABLE_HOST_AND_CHILD_NOTIFICATIONS", data.Hostname)
 }
 
 // HandleDisableHostCheck executes DISABLE_HOST_CHECK
@@ -534,8 +671,16 @@ func (a *Api) HandleDisableHostCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "DISABLE_HOST_CHECK", host.Hostname)
-	a.WriteCommandToFile(w, command)
+	if host.Hostname == "" {
+		http.Error(w, "Error: Hostname field is required", 400)
+		return
+	}
+
+	if !a.requireHost(w, r, host.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "DISABLE_HOST_CHECK", host.Hostname)
 }
 
 // HandleEnableHostCheck executes ENABLE_HOST_CHECK
@@ -549,8 +694,16 @@ func (a *Api) HandleEnableHostCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "ENABLE_HOST_CHECK", host.Hostname)
-	a.WriteCommandToFile(w, command)
+	if host.Hostname == "" {
+		http.Error(w, "Error: Hostname field is required", 400)
+		return
+	}
+
+	if !a.requireHost(w, r, host.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "ENABLE_HOST_CHECK", host.Hostname)
 }
 
 // HandleDisableHostNotifications executes DISABLE_HOST_NOTIFICATIONS
@@ -564,8 +717,16 @@ func (a *Api) HandleDisableHostNotifications(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "DISABLE_HOST_NOTIFICATIONS", host.Hostname)
-	a.WriteCommandToFile(w, command)
+	if host.Hostname == "" {
+		http.Error(w, "Error: Hostname field is required", 400)
+		return
+	}
+
+	if !a.requireHost(w, r, host.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "DISABLE_HOST_NOTIFICATIONS", host.Hostname)
 }
 
 // HandleEnableHostNotifications executes ENABLE_HOST_NOTIFICATIONS
@@ -579,22 +740,273 @@ func (a *Api) HandleEnableHostNotifications(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s", "ENABLE_HOST_NOTIFICATIONS", host.Hostname)
-	a.WriteCommandToFile(w, command)
+	if host.Hostname == "" {
+		http.Error(w, "Error: Hostname field is required", 400)
+		return
+	}
+
+	if !a.requireHost(w, r, host.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "ENABLE_HOST_NOTIFICATIONS", host.Hostname)
+}
+
+// HandleDisableServiceCheck executes DISABLE_SVC_CHECK
+// POST: /disable_svc_check -d '{"hostname": "host_name", "service_description": "service"}'
+func (a *Api) HandleDisableServiceCheck(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var data struct {
+		Hostname           string `json:"hostname"`
+		ServiceDescription string `json:"service_description"`
+	}
+	err := decoder.Decode(&data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), 400)
+		return
+	}
+
+	if data.Hostname == "" {
+		http.Error(w, fmt.Sprintf("Error: Hostname field is required"), 400)
+		return
+	}
+
+	if data.ServiceDescription == "" {
+		http.Error(w, fmt.Sprintf("Error: ServiceDescription field is required"), 400)
+		return
+	}
+
+	if !a.requireService(w, r, data.Hostname, data.ServiceDescription) {
+		return
+	}
+
+	a.executeCommand(w, r, "DISABLE_SVC_CHECK", data.Hostname, data.ServiceDescription)
+}
+
+// HandleEnableServiceCheck executes ENABLE_SVC_CHECK
+// POST: /enable_svc_check -d '{"hostname": "host_name", "service_description": "service"}'
+func (a *Api) HandleEnableServiceCheck(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var data struct {
+		Hostname           string `json:"hostname"`
+		ServiceDescription string `json:"service_description"`
+	}
+	err := decoder.Decode(&data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), 400)
+		return
+	}
+
+	if data.Hostname == "" {
+		http.Error(w, fmt.Sprintf("Error: Hostname field is required"), 400)
+		return
+	}
+
+	if data.ServiceDescription == "" {
+		http.Error(w, fmt.Sprintf("Error: ServiceDescription field is required"), 400)
+		return
+	}
+
+	if !a.requireService(w, r, data.Hostname, data.ServiceDescription) {
+		return
+	}
+
+	a.executeCommand(w, r, "ENABLE_SVC_CHECK", data.Hostname, data.ServiceDescription)
+}
+
+// HandleDisableServiceNotifications executes DISABLE_SVC_NOTIFICATIONS
+// POST: /disable_svc_notifications -d '{"hostname": "host_name", "service_description": "service"}'
+func (a *Api) HandleDisableServiceNotifications(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var data struct {
+		Hostname           string `json:"hostname"`
+		ServiceDescription string `json:"service_description"`
+	}
+	err := decoder.Decode(&data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), 400)
+		return
+	}
+
+	if data.Hostname == "" {
+		http.Error(w, fmt.Sprintf("Error: Hostname field is required"), 400)
+		return
+	}
+
+	if data.ServiceDescription == "" {
+		http.Error(w, fmt.Sprintf("Error: ServiceDescription field is required"), 400)
+		return
+	}
+
+	if !a.requireService(w, r, data.Hostname, data.ServiceDescription) {
+		return
+	}
+
+	a.executeCommand(w, r, "DISABLE_SVC_NOTIFICATIONS", data.Hostname, data.ServiceDescription)
+}
+
+// HandleEnableServiceNotifications executes ENABLE_SVC_NOTIFICATIONS
+// POST: /enable_svc_notifications -d '{"hostname": "host_name", "service_description": "service"}'
+func (a *Api) HandleEnableServiceNotifications(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var data struct {
+		Hostname           string `json:"hostname"`
+		ServiceDescription string `json:"service_description"`
+	}
+	err := decoder.Decode(&data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), 400)
+		return
+	}
+
+	if data.Hostname == "" {
+		http.Error(w, fmt.Sprintf("Error: Hostname field is required"), 400)
+		return
+	}
+
+	if data.ServiceDescription == "" {
+		http.Error(w, fmt.Sprintf("Error: ServiceDescription field is required"), 400)
+		return
+	}
+
+	if !a.requireService(w, r, data.Hostname, data.ServiceDescription) {
+		return
+	}
+
+	a.executeCommand(w, r, "ENABLE_SVC_NOTIFICATIONS", data.Hostname, data.ServiceDescription)
+}
+
+// HandleRemoveServiceAcknowledgement executes REMOVE_SVC_ACKNOWLEDGEMENT
+// POST: /remove_svc_acknowledgement -d '{"hostname": "host_name", "service_description": "service"}'
+func (a *Api) HandleRemoveServiceAcknowledgement(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var data struct {
+		Hostname           string `json:"hostname"`
+		ServiceDescription string `json:"service_description"`
+	}
+	err := decoder.Decode(&data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), 400)
+		return
+	}
+
+	if data.Hostname == "" {
+		http.Error(w, fmt.Sprintf("Error: Hostname field is required"), 400)
+		return
+	}
+
+	if data.ServiceDescription == "" {
+		http.Error(w, fmt.Sprintf("Error: ServiceDescription field is required"), 400)
+		return
+	}
+
+	if !a.requireService(w, r, data.Hostname, data.ServiceDescription) {
+		return
+	}
+
+	a.executeCommand(w, r, "REMOVE_SVC_ACKNOWLEDGEMENT", data.Hostname, data.ServiceDescription)
 }
 
 // HandleDisableNotifications executes DISABLE_NOTIFICATIONS
 // POST: /disable_notifications
 func (a *Api) HandleDisableNotifications(w http.ResponseWriter, r *http.Request) {
-	command := "DISABLE_NOTIFICATIONS"
-	a.WriteCommandToFile(w, command)
+	a.executeCommand(w, r, "DISABLE_NOTIFICATIONS")
 }
 
 // HandleEnableNotifications executes ENABLE_NOTIFICATIONS
 // POST: /enable_notifications
 func (a *Api) HandleEnableNotifications(w http.ResponseWriter, r *http.Request) {
-	command := "ENABLE_NOTIFICATIONS"
-	a.WriteCommandToFile(w, command)
+	a.executeCommand(w, r, "ENABLE_NOTIFICATIONS")
+}
+
+// HandleStartExecutingHostChecks executes START_EXECUTING_HOST_CHECKS
+// POST: /start_executing_host_checks
+func (a *Api) HandleStartExecutingHostChecks(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "START_EXECUTING_HOST_CHECKS")
+}
+
+// HandleStopExecutingHostChecks executes STOP_EXECUTING_HOST_CHECKS
+// POST: /stop_executing_host_checks
+func (a *Api) HandleStopExecutingHostChecks(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "STOP_EXECUTING_HOST_CHECKS")
+}
+
+// HandleStartExecutingServiceChecks executes START_EXECUTING_SVC_CHECKS
+// POST: /start_executing_svc_checks
+func (a *Api) HandleStartExecutingServiceChecks(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "START_EXECUTING_SVC_CHECKS")
+}
+
+// HandleStopExecutingServiceChecks executes STOP_EXECUTING_SVC_CHECKS
+// POST: /stop_executing_svc_checks
+func (a *Api) HandleStopExecutingServiceChecks(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "STOP_EXECUTING_SVC_CHECKS")
+}
+
+// HandleEnableEventHandlers executes ENABLE_EVENT_HANDLERS
+// POST: /enable_event_handlers
+func (a *Api) HandleEnableEventHandlers(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "ENABLE_EVENT_HANDLERS")
+}
+
+// HandleDisableEventHandlers executes DISABLE_EVENT_HANDLERS
+// POST: /disable_event_handlers
+func (a *Api) HandleDisableEventHandlers(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "DISABLE_EVENT_HANDLERS")
+}
+
+// HandleEnableFlapDetection executes ENABLE_FLAP_DETECTION
+// POST: /enable_flap_detection
+func (a *Api) HandleEnableFlapDetection(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "ENABLE_FLAP_DETECTION")
+}
+
+// HandleDisableFlapDetection executes DISABLE_FLAP_DETECTION
+// POST: /disable_flap_detection
+func (a *Api) HandleDisableFlapDetection(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "DISABLE_FLAP_DETECTION")
+}
+
+// HandleStartAcceptingPassiveHostChecks executes START_ACCEPTING_PASSIVE_HOST_CHECKS
+// POST: /start_accepting_passive_host_checks
+func (a *Api) HandleStartAcceptingPassiveHostChecks(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "START_ACCEPTING_PASSIVE_HOST_CHECKS")
+}
+
+// HandleStopAcceptingPassiveHostChecks executes STOP_ACCEPTING_PASSIVE_HOST_CHECKS
+// POST: /stop_accepting_passive_host_checks
+func (a *Api) HandleStopAcceptingPassiveHostChecks(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "STOP_ACCEPTING_PASSIVE_HOST_CHECKS")
+}
+
+// HandleStartAcceptingPassiveServiceChecks executes START_ACCEPTING_PASSIVE_SVC_CHECKS
+// POST: /start_accepting_passive_svc_checks
+func (a *Api) HandleStartAcceptingPassiveServiceChecks(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "START_ACCEPTING_PASSIVE_SVC_CHECKS")
+}
+
+// HandleStopAcceptingPassiveServiceChecks executes STOP_ACCEPTING_PASSIVE_SVC_CHECKS
+// POST: /stop_accepting_passive_svc_checks
+func (a *Api) HandleStopAcceptingPassiveServiceChecks(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "STOP_ACCEPTING_PASSIVE_SVC_CHECKS")
+}
+
+// HandleRestartProgram executes RESTART_PROGRAM
+// POST: /restart_program
+func (a *Api) HandleRestartProgram(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "RESTART_PROGRAM")
+}
+
+// HandleShutdownProgram executes SHUTDOWN_PROGRAM
+// POST: /shutdown_program
+func (a *Api) HandleShutdownProgram(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "SHUTDOWN_PROGRAM")
+}
+
+// HandleSaveStateInformation executes SAVE_STATE_INFORMATION
+// POST: /save_state_information
+func (a *Api) HandleSaveStateInformation(w http.ResponseWriter, r *http.Request) {
+	a.executeCommand(w, r, "SAVE_STATE_INFORMATION")
 }
 
 // HandleScheduleForcedHostCheck executes SCHEDULE_FORCED_HOST_CHECK
@@ -608,8 +1020,16 @@ func (a *Api) HandleScheduleForcedHostCheck(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s;[%d]", "SCHEDULE_FORCED_HOST_CHECK", host.Hostname, time.Now().Unix())
-	a.WriteCommandToFile(w, command)
+	if host.Hostname == "" {
+		http.Error(w, "Error: Hostname field is required", 400)
+		return
+	}
+
+	if !a.requireHost(w, r, host.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "SCHEDULE_FORCED_HOST_CHECK", host.Hostname, time.Now().Unix())
 }
 
 // HandleScheduleForcedHostServiceChecks executes SCHEDULE_FORCED_HOST_SVC_CHECKS
@@ -623,18 +1043,94 @@ func (a *Api) HandleScheduleForcedHostServiceChecks(w http.ResponseWriter, r *ht
 		return
 	}
 
-	command := fmt.Sprintf("%s;%s;[%d]", "SCHEDULE_FORCED_HOST_SVC_CHECKS", host.Hostname, time.Now().Unix())
-	a.WriteCommandToFile(w, command)
+	if host.Hostname == "" {
+		http.Error(w, "Error: Hostname field is required", 400)
+		return
+	}
+
+	if !a.requireHost(w, r, host.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "SCHEDULE_FORCED_HOST_SVC_CHECKS", host.Hostname, time.Now().Unix())
 }
 
 // HandleScheduleForcedServiceCheck executes SCHEDULE_FORCED_SVC_CHECK
 // SCHEDULE_FORCED_SVC_CHECK;<host_name>;<service_description>;<check_time>
 func (a *Api) HandleScheduleForcedServiceCheck(w http.ResponseWriter, r *http.Request) {
+	a.scheduleServiceCheck(w, r, "SCHEDULE_FORCED_SVC_CHECK")
+}
+
+// HandleScheduleServiceCheck executes SCHEDULE_SVC_CHECK
+// SCHEDULE_SVC_CHECK;<host_name>;<service_description>;<check_time>
+func (a *Api) HandleScheduleServiceCheck(w http.ResponseWriter, r *http.Request) {
+	a.scheduleServiceCheck(w, r, "SCHEDULE_SVC_CHECK")
+}
+
+// scheduleServiceCheck decodes {"hostname", "service_description", "check_time"} and writes
+// the given check scheduling command, checking now when no check_time is given
+func (a *Api) scheduleServiceCheck(w http.ResponseWriter, r *http.Request, name string) {
+	decoder := json.NewDecoder(r.Body)
+	var data struct {
+		Hostname           string `json:"hostname"`
+		ServiceDescription string `json:"service_description"`
+		CheckTime          int64  `json:"check_time"`
+	}
+	err := decoder.Decode(&data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), 400)
+		return
+	}
+
+	if data.Hostname == "" {
+		http.Error(w, fmt.Sprintf("Error: Hostname field is required"), 400)
+		return
+	}
+
+	if data.ServiceDescription == "" {
+		http.Error(w, fmt.Sprintf("Error: ServiceDescription field is required"), 400)
+		return
+	}
+
+	if data.CheckTime == 0 {
+		data.CheckTime = time.Now().Unix()
+	}
+
+	if !a.requireService(w, r, data.Hostname, data.ServiceDescription) {
+		return
+	}
+
+	a.executeCommand(w, r, name, data.Hostname, data.ServiceDescription, data.CheckTime)
 }
 
 // HandleScheduleHostCheck executes SCHEDULE_HOST_CHECK
 // SCHEDULE_HOST_CHECK;<host_name>;<check_time>
 func (a *Api) HandleScheduleHostCheck(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var data struct {
+		Hostname  string `json:"hostname"`
+		CheckTime int64  `json:"check_time"`
+	}
+	err := decoder.Decode(&data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), 400)
+		return
+	}
+
+	if data.Hostname == "" {
+		http.Error(w, fmt.Sprintf("Error: Hostname field is required"), 400)
+		return
+	}
+
+	if data.CheckTime == 0 {
+		data.CheckTime = time.Now().Unix()
+	}
+
+	if !a.requireHost(w, r, data.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "SCHEDULE_HOST_CHECK", data.Hostname, data.CheckTime)
 }
 
 // HandleScheduleHostDowntime executes SCHEDULE_HOST_DOWNTIME
@@ -674,20 +1170,288 @@ func (a *Api) HandleScheduleHostDowntime(w http.ResponseWriter, r *http.Request)
 
 	if data.StartTime >= data.EndTime {
 		http.Error(w, "start_time must be less than end_time", http.StatusBadRequest)
+		return
+	}
+
+	if data.Duration == 0 {
+		http.Error(w, "duration of maintenance must be greater than 0 seconds", http.StatusBadRequest)
+		return
+	}
+
+	if !a.requireHost(w, r, data.Hostname) {
+		return
+	}
+
+	a.executeCommand(w, r, "SCHEDULE_HOST_DOWNTIME", data.Hostname, data.StartTime, data.EndTime, data.Fixed, data.TriggerID, data.Duration, data.Author, text(data.Comment))
+}
+
+// HandleScheduleServiceDowntime executes SCHEDULE_SVC_DOWNTIME
+// SCHEDULE_SVC_DOWNTIME;<host_name>;<service_description>;<start_time>;<end_time>;<fixed>;<trigger_id>;<duration>;<author>;<comment>
+func (a *Api) HandleScheduleServiceDowntime(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var data struct {
+		Hostname           string `json:"hostname"`
+		ServiceDescription string `json:"service_description"`
+		StartTime          int64  `json:"start_time"`
+		EndTime            int64  `json:"end_time"`
+		Fixed              uint8  `json:"fixed"`
+		TriggerID          int64  `json:"trigger_id"`
+		Duration           int64  `json:"duration"`
+		Author             string `json:"author"`
+		Comment            string `json:"comment"`
+	}
+	err := decoder.Decode(&data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if data.Hostname == "" {
+		http.Error(w, "Missing host", http.StatusBadRequest)
+		return
+	}
+
+	if data.ServiceDescription == "" {
+		http.Error(w, "Missing service_description", http.StatusBadRequest)
+		return
+	}
+
+	if data.Author == "" {
+		http.Error(w, "Error: Author field is required", http.StatusBadRequest)
+		return
+	}
+
+	if data.Comment == "" {
+		http.Error(w, "Error: Comment can not be empty", http.StatusBadRequest)
+		return
+	}
+
+	if data.StartTime >= data.EndTime {
+		http.Error(w, "start_time must be less than end_time", http.StatusBadRequest)
+		return
 	}
 
 	if data.Duration == 0 {
 		http.Error(w, "duration of maintenance must be greater than 0 seconds", http.StatusBadRequest)
+		return
+	}
+
+	if !a.requireService(w, r, data.Hostname, data.ServiceDescription) {
+		return
+	}
+
+	a.executeCommand(w, r, "SCHEDULE_SVC_DOWNTIME", data.Hostname, data.ServiceDescription, data.StartTime, data.EndTime, data.Fixed, data.TriggerID, data.Duration, data.Author, text(data.Comment))
+}
+
+type passiveCheckResult struct {
+	Hostname           string `json:"hostname"`
+	ServiceDescription string `json:"service_description"`
+	ReturnCode         *int   `json:"return_code"`
+	Output             string `json:"output"`
+	PerfData           string `json:"perfdata"`
+}
+
+// pluginOutput joins output and perfdata the way a plugin prints them, with
+// newlines escaped so multi-line output stays on a single command line
+func (c passiveCheckResult) pluginOutput() string {
+	output := c.Output
+	if c.PerfData != "" {
+		output = output + "|" + c.PerfData
+	}
+	return strings.Replace(output, "\n", `\n`, -1)
+}
+
+// command renders PROCESS_HOST_CHECK_RESULT, or PROCESS_SERVICE_CHECK_RESULT when a service is set
+func (c passiveCheckResult) command() (string, error) {
+	if c.ServiceDescription == "" {
+		return buildCommand("PROCESS_HOST_CHECK_RESULT", c.Hostname, *c.ReturnCode, text(c.pluginOutput()))
+	}
+	return buildCommand("PROCESS_SERVICE_CHECK_RESULT", c.Hostname, c.ServiceDescription, *c.ReturnCode, text(c.pluginOutput()))
+}
+
+// decodePassiveCheckResults accepts either a single result object or a list of them
+func decodePassiveCheckResults(r *http.Request) ([]passiveCheckResult, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	var results []passiveCheckResult
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(raw, &results); err != nil {
+			return nil, err
+		}
+		return results, nil
 	}
 
-	command := fmt.Sprintf("%s;%s;%d;%d;%d;%d;%d;%s;%s", "SCHEDULE_HOST_DOWNTIME", data.Hostname, data.StartTime, data.EndTime, data.Fixed, data.TriggerID, data.Duration, data.Author, data.Comment)
-	a.WriteCommandToFile(w, command)
+	var result passiveCheckResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, err
+	}
+	return append(results, result), nil
 }
 
-// WriteCommandToFile writes command to nagios command file
-func (a *Api) WriteCommandToFile(w http.ResponseWriter, command string) {
-	if err := a.WriteCommand(command); err != nil {
-		http.Error(w, "Could not execute command", http.StatusInternalServerError)
+// HandleProcessHostCheckResult executes PROCESS_HOST_CHECK_RESULT for one or many results
+// PROCESS_HOST_CHECK_RESULT;<host_name>;<status_code>;<plugin_output>
+// POST: /process_host_check_result
+//       {hostname:string, return_code:int, output:string, perfdata:string} or a list of them
+func (a *Api) HandleProcessHostCheckResult(w http.ResponseWriter, r *http.Request) {
+	results, err := decodePassiveCheckResults(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), 400)
 		return
 	}
+
+	if len(results) == 0 {
+		http.Error(w, "Error: No check results provided", 400)
+		return
+	}
+
+	snap := a.snapshotOf(r)
+
+	var commands []string
+	for i, result := range results {
+		if result.Hostname == "" {
+			http.Error(w, fmt.Sprintf("Error: Hostname field is required (result %d)", i), 400)
+			return
+		}
+
+		if result.ReturnCode == nil || *result.ReturnCode < 0 || *result.ReturnCode > 2 {
+			http.Error(w, fmt.Sprintf("Error: return_code must be 0 (UP), 1 (DOWN) or 2 (UNREACHABLE) (result %d)", i), 400)
+			return
+		}
+
+		if !snap.static.hasHost(result.Hostname) {
+			http.Error(w, fmt.Sprintf("Error: Unknown host %s (result %d)", result.Hostname, i), 404)
+			return
+		}
+
+		// A host result never targets a service, even if one was sent
+		result.ServiceDescription = ""
+		command, err := result.command()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error: %s (result %d)", err, i), 400)
+			return
+		}
+		commands = append(commands, command)
+	}
+
+	dryRun, err := a.submitCommands(r, commands)
+	if err != nil {
+		replyCommandError(w, err)
+		return
+	}
+	if dryRun {
+		replyDryRun(w, commands)
+	}
+}
+
+// HandleProcessServiceCheckResult executes PROCESS_SERVICE_CHECK_RESULT for one or many results
+// PROCESS_SERVICE_CHECK_RESULT;<host_name>;<svc_description>;<return_code>;<plugin_output>
+// POST: /process_service_check_result
+//       {hostname:string, service_description:string, return_code:int, output:string, perfdata:string} or a list of them
+func (a *Api) HandleProcessServiceCheckResult(w http.ResponseWriter, r *http.Request) {
+	results, err := decodePassiveCheckResults(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), 400)
+		return
+	}
+
+	if len(results) == 0 {
+		http.Error(w, "Error: No check results provided", 400)
+		return
+	}
+
+	snap := a.snapshotOf(r)
+
+	var commands []string
+	for i, result := range results {
+		if result.Hostname == "" {
+			http.Error(w, fmt.Sprintf("Error: Hostname field is required (result %d)", i), 400)
+			return
+		}
+
+		if result.ServiceDescription == "" {
+			http.Error(w, fmt.Sprintf("Error: ServiceDescription field is required (result %d)", i), 400)
+			return
+		}
+
+		if result.ReturnCode == nil || *result.ReturnCode < 0 || *result.ReturnCode > 3 {
+			http.Error(w, fmt.Sprintf("Error: return_code must be 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN) (result %d)", i), 400)
+			return
+		}
+
+		if !snap.static.hasService(result.Hostname, result.ServiceDescription) {
+			http.Error(w, fmt.Sprintf("Error: Unknown service %s on host %s (result %d)", result.ServiceDescription, result.Hostname, i), 404)
+			return
+		}
+
+		command, err := result.command()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error: %s (result %d)", err, i), 400)
+			return
+		}
+		commands = append(commands, command)
+	}
+
+	dryRun, err := a.submitCommands(r, commands)
+	if err != nil {
+		replyCommandError(w, err)
+		return
+	}
+	if dryRun {
+		replyDryRun(w, commands)
+	}
+}
+
+// requireHost replies 404 and returns false when host is not defined in objects.cache
+func (a *Api) requireHost(w http.ResponseWriter, r *http.Request, host string) bool {
+	if !a.snapshotOf(r).static.hasHost(host) {
+		http.Error(w, fmt.Sprintf("Error: Unknown host %s", host), http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// requireService replies 404 and returns false when service is not defined for host in objects.cache
+func (a *Api) requireService(w http.ResponseWriter, r *http.Request, host, service string) bool {
+	if !a.snapshotOf(r).static.hasService(host, service) {
+		http.Error(w, fmt.Sprintf("Error: Unknown service %s on host %s", service, host), http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// requireHostgroup replies 404 and returns false when hostgroup is not defined in objects.cache
+func (a *Api) requireHostgroup(w http.ResponseWriter, r *http.Request, group string) bool {
+	if !a.snapshotOf(r).static.hasHostgroup(group) {
+		http.Error(w, fmt.Sprintf("Error: Unknown hostgroup %s", group), http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// executeCommand builds the command from its name and arguments and writes it to nagios
+// command file, replying 400 when an argument can not be written safely
+func (a *Api) executeCommand(w http.ResponseWriter, r *http.Request, name string, args ...interface{}) {
+	command, err := buildCommand(name, args...)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	a.submitCommand(w, r, command)
+}
+
+// submitCommand writes command to nagios command file, or replies with the line that would
+// have been written in dry run
+func (a *Api) submitCommand(w http.ResponseWriter, r *http.Request, command string) {
+	dryRun, err := a.submitCommands(r, []string{command})
+	if err != nil {
+		replyCommandError(w, err)
+		return
+	}
+	if dryRun {
+		replyDryRun(w, []string{command})
+	}
 }
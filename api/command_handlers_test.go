@@ -0,0 +1,309 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sebor/nagios-api/auth"
+	"github.com/cheekybits/is"
+)
+
+// newTestApi returns an Api writing to an empty command file in a temp dir,
+// with static data read from testdata/objects.cache
+func newTestApi(t *testing.T) *Api {
+	dir, err := ioutil.TempDir("", "nagios-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	commandFile := filepath.Join(dir, "nagios.cmd")
+	if err := ioutil.WriteFile(commandFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	oc, err := os.Open("testdata/objects.cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer oc.Close()
+
+	api := NewAPI(":0", "testdata/objects.cache", commandFile, "testdata/status.dat", []string{"secret"}, false)
+	data, err := readObjectCache(oc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.publishStatic(data, time.Time{})
+	return api
+}
+
+// writtenCommands returns the commands written to the command file without their timestamps
+func writtenCommands(t *testing.T, api *Api) []string {
+	dat, err := ioutil.ReadFile(api.fileCommand)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var commands []string
+	for _, line := range strings.Split(strings.TrimSpace(string(dat)), "\n") {
+		if line == "" {
+			continue
+		}
+		commands = append(commands, strings.SplitN(line, "] ", 2)[1])
+	}
+	return commands
+}
+
+func TestCommandHandlers(t *testing.T) {
+	host := `{"hostname": "web01"}`
+	hostgroup := `{"hostgroup": "web-servers"}`
+	service := `{"hostname": "web01", "service_description": "HTTP"}`
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		code    int
+		command string
+		// prefix is set for commands stamped with the current time
+		prefix bool
+	}{
+		{name: "disable notifications", path: "/disable_notifications", code: 200, command: "DISABLE_NOTIFICATIONS"},
+		{name: "enable notifications", path: "/enable_notifications", code: 200, command: "ENABLE_NOTIFICATIONS"},
+		{name: "start executing host checks", path: "/start_executing_host_checks", code: 200, command: "START_EXECUTING_HOST_CHECKS"},
+		{name: "stop executing host checks", path: "/stop_executing_host_checks", code: 200, command: "STOP_EXECUTING_HOST_CHECKS"},
+		{name: "start executing svc checks", path: "/start_executing_svc_checks", code: 200, command: "START_EXECUTING_SVC_CHECKS"},
+		{name: "stop executing svc checks", path: "/stop_executing_svc_checks", code: 200, command: "STOP_EXECUTING_SVC_CHECKS"},
+		{name: "enable event handlers", path: "/enable_event_handlers", code: 200, command: "ENABLE_EVENT_HANDLERS"},
+		{name: "disable event handlers", path: "/disable_event_handlers", code: 200, command: "DISABLE_EVENT_HANDLERS"},
+		{name: "enable flap detection", path: "/enable_flap_detection", code: 200, command: "ENABLE_FLAP_DETECTION"},
+		{name: "disable flap detection", path: "/disable_flap_detection", code: 200, command: "DISABLE_FLAP_DETECTION"},
+		{name: "start accepting passive host checks", path: "/start_accepting_passive_host_checks", code: 200, command: "START_ACCEPTING_PASSIVE_HOST_CHECKS"},
+		{name: "stop accepting passive host checks", path: "/stop_accepting_passive_host_checks", code: 200, command: "STOP_ACCEPTING_PASSIVE_HOST_CHECKS"},
+		{name: "start accepting passive svc checks", path: "/start_accepting_passive_svc_checks", code: 200, command: "START_ACCEPTING_PASSIVE_SVC_CHECKS"},
+		{name: "stop accepting passive svc checks", path: "/stop_accepting_passive_svc_checks", code: 200, command: "STOP_ACCEPTING_PASSIVE_SVC_CHECKS"},
+		{name: "restart program", path: "/restart_program", code: 200, command: "RESTART_PROGRAM"},
+		{name: "shutdown program", path: "/shutdown_program", code: 200, command: "SHUTDOWN_PROGRAM"},
+		{name: "save state information", path: "/save_state_information", code: 200, command: "SAVE_STATE_INFORMATION"},
+
+		{name: "disable host check", path: "/disable_host_check", body: host, code: 200, command: "DISABLE_HOST_CHECK;web01"},
+		{name: "enable host check", path: "/enable_host_check", body: host, code: 200, command: "ENABLE_HOST_CHECK;web01"},
+		{name: "disable host notifications", path: "/disable_host_notifications", body: host, code: 200, command: "DISABLE_HOST_NOTIFICATIONS;web01"},
+		{name: "enable host notifications", path: "/enable_host_notifications", body: host, code: 200, command: "ENABLE_HOST_NOTIFICATIONS;web01"},
+		{name: "del all host comment", path: "/del_all_host_comment", body: host, code: 200, command: "DEL_ALL_HOST_COMMENTS;web01"},
+		{name: "del all svc comment", path: "/del_all_svc_comment", body: host, code: 200, command: "DEL_ALL_SVC_COMMENTS;web01"},
+		{name: "disable all notification beyond host", path: "/disable_all_notification_beyond_host", body: host, code: 200, command: "DISABLE_ALL_NOTIFICATIONS_BEYOND_HOST;web01"},
+		{name: "enable all notification beyond host", path: "/enable_all_notification_beyond_host", body: host, code: 200, command: "ENABLE_ALL_NOTIFICATIONS_BEYOND_HOST;web01"},
+		{name: "disable host and child notifications", path: "/disable_host_and_child_notifications", body: host, code: 200, command: "DISABLE_HOST_AND_CHILD_NOTIFICATIONS;web01"},
+		{name: "enable host and child notifications", path: "/enable_host_and_child_notifications", body: host, code: 200, command: "ENABLE_HOST_AND_CHILD_NOTIFICATIONS;web01"},
+		{name: "force host checks", path: "/force_host_checks", body: host, code: 200, command: "SCHEDULE_FORCED_HOST_CHECK;web01;", prefix: true},
+		{name: "force service checks", path: "/force_service_checks", body: host, code: 200, command: "SCHEDULE_FORCED_HOST_SVC_CHECKS;web01;", prefix: true},
+		{name: "force service checks by url", method: http.MethodGet, path: "/host/web01/force", code: 200, command: "SCHEDULE_FORCED_HOST_SVC_CHECKS;web01;", prefix: true},
+		{name: "schedule host check", path: "/schedule_host_check", body: `{"hostname": "web01", "check_time": 1484082200}`, code: 200, command: "SCHEDULE_HOST_CHECK;web01;1484082200"},
+
+		{name: "disable hostgroup host checks", path: "/disable_hostgroup_host_checks", body: hostgroup, code: 200, command: "DISABLE_HOSTGROUP_HOST_CHECKS;web-servers"},
+		{name: "enable hostgroup host checks", path: "/enable_hostgroup_host_checks", body: hostgroup, code: 200, command: "ENABLE_HOSTGROUP_HOST_CHECKS;web-servers"},
+		{name: "disable hostgroup host notifications", path: "/disable_hostgroup_host_notifications", body: hostgroup, code: 200, command: "DISABLE_HOSTGROUP_HOST_NOTIFICATIONS;web-servers"},
+		{name: "enable hostgroup host notifications", path: "/enable_hostgroup_host_notifications", body: hostgroup, code: 200, command: "ENABLE_HOSTGROUP_HOST_NOTIFICATIONS;web-servers"},
+		{name: "disable hostgroup svc checks", path: "/disable_hostgroup_svc_checks", body: hostgroup, code: 200, command: "DISABLE_HOSTGROUP_SVC_CHECKS;web-servers"},
+		{name: "enable hostgroup svc checks", path: "/enable_hostgroup_svc_checks", body: hostgroup, code: 200, command: "ENABLE_HOSTGROUP_SVC_CHECKS;web-servers"},
+		{name: "disable hostgroup svc notifications", path: "/disable_hostgroup_svc_notifications", body: hostgroup, code: 200, command: "DISABLE_HOSTGROUP_SVC_NOTIFICATIONS;web-servers"},
+		{name: "enable hostgroup svc notifications", path: "/enable_hostgroup_svc_notifications", body: hostgroup, code: 200, command: "ENABLE_HOSTGROUP_SVC_NOTIFICATIONS;web-servers"},
+
+		{name: "disable svc check", path: "/disable_svc_check", body: service, code: 200, command: "DISABLE_SVC_CHECK;web01;HTTP"},
+		{name: "enable svc check", path: "/enable_svc_check", body: service, code: 200, command: "ENABLE_SVC_CHECK;web01;HTTP"},
+		{name: "disable svc notifications", path: "/disable_svc_notifications", body: service, code: 200, command: "DISABLE_SVC_NOTIFICATIONS;web01;HTTP"},
+		{name: "enable svc notifications", path: "/enable_svc_notifications", body: service, code: 200, command: "ENABLE_SVC_NOTIFICATIONS;web01;HTTP"},
+		{name: "remove svc acknowledgement", path: "/remove_svc_acknowledgement", body: service, code: 200, command: "REMOVE_SVC_ACKNOWLEDGEMENT;web01;HTTP"},
+		{name: "schedule svc check", path: "/schedule_svc_check", body: `{"hostname": "web01", "service_description": "HTTP", "check_time": 1484082200}`, code: 200, command: "SCHEDULE_SVC_CHECK;web01;HTTP;1484082200"},
+		{name: "force svc check", path: "/force_svc_check", body: `{"hostname": "web01", "service_description": "HTTP", "check_time": 1484082200}`, code: 200, command: "SCHEDULE_FORCED_SVC_CHECK;web01;HTTP;1484082200"},
+
+		{name: "acknowledge host problem", path: "/acknowledge_host_problem", body: `{"hostname": "web01", "author": "jason", "comment": "on it"}`, code: 200, command: "ACKNOWLEDGE_HOST_PROBLEM;web01;2;1;1;jason;on it"},
+		{name: "acknowledge service problem", path: "/acknowledge_service_problem", body: `{"hostname": "web01", "servicedescription": "HTTP", "sticky": 1, "author": "jason", "comment": "on it"}`, code: 200, command: "ACKNOWLEDGE_SVC_PROBLEM;web01;HTTP;1;1;1;jason;on it"},
+		{name: "add host comment", path: "/add_host_comment", body: `{"hostname": "web01", "author": "jason", "comment": "a; b"}`, code: 200, command: "ADD_HOST_COMMENT;web01;1;jason;a; b"},
+		{name: "add svc comment", path: "/add_svc_comment", body: `{"hostname": "web01", "service": "HTTP", "author": "jason", "comment": "hello"}`, code: 200, command: "ADD_SVC_COMMENT;web01;HTTP;1;jason;hello"},
+		{name: "del host comment", path: "/del_host_comment", body: `{"commentid": "4"}`, code: 200, command: "DEL_HOST_COMMENT;4"},
+		{name: "del svc comment", path: "/del_svc_comment", body: `{"commentid": "7"}`, code: 200, command: "DEL_SVC_COMMENT;7"},
+
+		{name: "schedule host downtime", path: "/schedule_host_downtime", body: `{"hostname": "web01", "start_time": 100, "end_time": 200, "fixed": 1, "duration": 100, "author": "jason", "comment": "maintenance"}`, code: 200, command: "SCHEDULE_HOST_DOWNTIME;web01;100;200;1;0;100;jason;maintenance"},
+		{name: "schedule host downtime ends before start", path: "/schedule_host_downtime", body: `{"hostname": "web01", "start_time": 200, "end_time": 100, "duration": 100, "author": "jason", "comment": "maintenance"}`, code: 400},
+		{name: "schedule svc downtime", path: "/schedule_svc_downtime", body: `{"hostname": "web01", "service_description": "HTTP", "start_time": 100, "end_time": 200, "duration": 100, "author": "jason", "comment": "deploy"}`, code: 200, command: "SCHEDULE_SVC_DOWNTIME;web01;HTTP;100;200;0;0;100;jason;deploy"},
+		{name: "del host downtime", path: "/del_host_downtime", body: `{"downtimeid": "1"}`, code: 200, command: "DEL_HOST_DOWNTIME;1"},
+		{name: "del svc downtime", path: "/del_svc_downtime", body: `{"downtimeid": "2"}`, code: 200, command: "DEL_SVC_DOWNTIME;2"},
+		{name: "del downtime by host name", path: "/del_downtime_by_host_name", body: host, code: 200, command: "DEL_DOWNTIME_BY_HOST_NAME;web01"},
+		{name: "del downtime by host name and comment", path: "/del_downtime_by_host_name", body: `{"hostname": "web01", "comment": "deploy"}`, code: 200, command: "DEL_DOWNTIME_BY_HOST_NAME;web01;;;deploy"},
+
+		// Unknown targets
+		{name: "missing hostname", path: "/disable_host_check", body: `{}`, code: 400},
+		{name: "unknown host", path: "/disable_host_check", body: `{"hostname": "nosuchhost"}`, code: 404},
+		{name: "unknown host by url", method: http.MethodGet, path: "/host/nosuchhost/force", code: 404},
+		{name: "unknown host for comment", path: "/add_host_comment", body: `{"hostname": "nosuchhost", "author": "jason", "comment": "hello"}`, code: 404},
+		{name: "unknown service", path: "/disable_svc_check", body: `{"hostname": "web01", "service_description": "MySQL"}`, code: 404},
+		{name: "unknown service for downtime deletion", path: "/del_downtime_by_host_name", body: `{"hostname": "web01", "service_description": "MySQL"}`, code: 404},
+		{name: "unknown hostgroup", path: "/enable_hostgroup_svc_checks", body: `{"hostgroup": "nosuchgroup"}`, code: 404},
+
+		// Injection attempts
+		{name: "newline in hostname", path: "/disable_host_check", body: `{"hostname": "web01\n[0] SHUTDOWN_PROGRAM"}`, code: 404},
+		{name: "semicolon in hostname", path: "/disable_host_notifications", body: `{"hostname": "web01;x"}`, code: 404},
+		{name: "semicolon in hostgroup", path: "/disable_hostgroup_host_checks", body: `{"hostgroup": "web-servers;x"}`, code: 404},
+		{name: "semicolon in service", path: "/disable_svc_check", body: `{"hostname": "web01", "service_description": "HTTP;x"}`, code: 404},
+		{name: "newline in comment", path: "/add_host_comment", body: `{"hostname": "web01", "author": "jason", "comment": "hi\n[0] SHUTDOWN_PROGRAM"}`, code: 400},
+		{name: "semicolon in author", path: "/acknowledge_host_problem", body: `{"hostname": "web01", "author": "jason;x", "comment": "on it"}`, code: 400},
+		{name: "carriage return in downtime comment", path: "/schedule_host_downtime", body: `{"hostname": "web01", "start_time": 100, "end_time": 200, "duration": 100, "author": "jason", "comment": "x\r\n[0] SHUTDOWN_PROGRAM"}`, code: 400},
+		{name: "newline in comment id", path: "/del_host_comment", body: `{"commentid": "4\nSHUTDOWN_PROGRAM"}`, code: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			api := newTestApi(t)
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(method, tt.path, strings.NewReader(tt.body))
+			api.router.ServeHTTP(w, r)
+
+			is.Equal(w.Code, tt.code)
+			commands := writtenCommands(t, api)
+			if tt.command == "" {
+				is.Equal(len(commands), 0)
+				return
+			}
+			is.Equal(len(commands), 1)
+			if tt.prefix {
+				is.True(strings.HasPrefix(commands[0], tt.command))
+			} else {
+				is.Equal(commands[0], tt.command)
+			}
+		})
+	}
+}
+
+func TestProcessCheckResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		code     int
+		commands []string
+	}{
+		{
+			name:     "single service result",
+			path:     "/process_service_check_result",
+			body:     `{"hostname": "web01", "service_description": "HTTP", "return_code": 2, "output": "connection refused", "perfdata": "time=0s"}`,
+			code:     200,
+			commands: []string{"PROCESS_SERVICE_CHECK_RESULT;web01;HTTP;2;connection refused|time=0s"},
+		},
+		{
+			name: "many host results",
+			path: "/process_host_check_result",
+			body: `[{"hostname": "web01", "return_code": 0, "output": "UP"}, {"hostname": "db01", "return_code": 1, "output": "line1\nline2"}]`,
+			code: 200,
+			commands: []string{
+				"PROCESS_HOST_CHECK_RESULT;web01;0;UP",
+				`PROCESS_HOST_CHECK_RESULT;db01;1;line1\nline2`,
+			},
+		},
+		{
+			name: "missing return code",
+			path: "/process_host_check_result",
+			body: `{"hostname": "web01", "output": "UP"}`,
+			code: 400,
+		},
+		{
+			name: "service return code out of range",
+			path: "/process_service_check_result",
+			body: `{"hostname": "web01", "service_description": "HTTP", "return_code": 4}`,
+			code: 400,
+		},
+		{
+			name: "unknown service",
+			path: "/process_service_check_result",
+			body: `{"hostname": "web01", "service_description": "SSH", "return_code": 0}`,
+			code: 404,
+		},
+		{
+			name: "semicolon in hostname",
+			path: "/process_host_check_result",
+			body: `{"hostname": "web01;x", "return_code": 0}`,
+			code: 404,
+		},
+		{
+			name:     "semicolon in output",
+			path:     "/process_host_check_result",
+			body:     `{"hostname": "web01", "return_code": 0, "output": "a;b"}`,
+			code:     200,
+			commands: []string{"PROCESS_HOST_CHECK_RESULT;web01;0;a;b"},
+		},
+		{
+			name: "one unknown host rejects the whole batch",
+			path: "/process_host_check_result",
+			body: `[{"hostname": "web01", "return_code": 0}, {"hostname": "nosuchhost", "return_code": 0}]`,
+			code: 404,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			api := newTestApi(t)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			api.router.ServeHTTP(w, r)
+
+			is.Equal(w.Code, tt.code)
+			is.Equal(strings.Join(writtenCommands(t, api), "\n"), strings.Join(tt.commands, "\n"))
+		})
+	}
+}
+
+func TestRoutesAuthentication(t *testing.T) {
+	authenticator, err := auth.New(map[string]string{"ci": "s3cret"}, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.Configure(authenticator)
+	defer auth.Configure(nil)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		code   int
+	}{
+		{name: "anonymous read", method: http.MethodGet, path: "/hosts", code: 200},
+		{name: "anonymous command", method: http.MethodPost, path: "/disable_notifications", code: 401},
+		{name: "anonymous forced checks", method: http.MethodGet, path: "/host/web01/force", code: 401},
+		{name: "anonymous audit", method: http.MethodGet, path: "/audit", code: 401},
+		{name: "command with api key", method: http.MethodPost, path: "/disable_notifications", key: "s3cret", code: 200},
+		{name: "nrdp token", method: http.MethodPost, path: "/nrdp/?token=secret&cmd=submitcheck&XMLDATA=%3Ccheckresults%3E%3C%2Fcheckresults%3E", code: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			api := newTestApi(t)
+
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			api.router.ServeHTTP(w, r)
+			is.Equal(w.Code, tt.code)
+		})
+	}
+}
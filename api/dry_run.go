@@ -0,0 +1,28 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+type dryRunResult struct {
+	DryRun   bool     `json:"dry_run"`
+	Commands []string `json:"commands"`
+}
+
+// dryRun reports whether the commands of a request must be validated but not written, either
+// because the API runs in dry run mode or because the request asks for it with dry_run=true
+func (a *Api) dryRun(r *http.Request) bool {
+	if a.dryRunMode {
+		return true
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	return dryRun
+}
+
+// replyDryRun sends the command lines that would have been written to nagios command file
+func replyDryRun(w http.ResponseWriter, commands []string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dryRunResult{DryRun: true, Commands: commandLines(commands)})
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+)
+
+func TestDryRun(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+		// global runs the API in dry run mode instead of passing dry_run=true
+		global bool
+		code   int
+		line   string
+	}{
+		{name: "legacy handler", path: "/disable_host_check?dry_run=true", body: `{"hostname": "web01"}`, code: 200, line: "DISABLE_HOST_CHECK;web01"},
+		{name: "legacy handler in dry run mode", path: "/disable_host_check", body: `{"hostname": "web01"}`, global: true, code: 200, line: "DISABLE_HOST_CHECK;web01"},
+		{name: "legacy handler validation", path: "/disable_host_check?dry_run=true", body: `{"hostname": "nope"}`, code: 404},
+		{name: "catalogue command", path: "/commands/ADD_SVC_COMMENT?dry_run=1", body: `{"host_name": "web01", "service_description": "HTTP", "author": "jason", "comment": "test"}`, code: 200, line: "ADD_SVC_COMMENT;web01;HTTP;1;jason;test"},
+		{name: "catalogue command with confirmation", path: "/commands/DISABLE_NOTIFICATIONS?confirm=true", global: true, code: 200, line: "DISABLE_NOTIFICATIONS"},
+		{name: "catalogue command validation", path: "/commands/ADD_SVC_COMMENT?dry_run=true", body: `{"host_name": "web01"}`, code: 400},
+		{name: "check result", path: "/process_service_check_result?dry_run=true", body: `{"hostname": "db01", "service_description": "MySQL", "return_code": 2, "output": "down"}`, code: 200, line: "PROCESS_SERVICE_CHECK_RESULT;db01;MySQL;2;down"},
+		{name: "batch", path: "/commands?dry_run=true", body: `[{"command": "ENABLE_HOST_CHECK", "params": {"host_name": "db01"}}, {"command": "NOPE"}]`, code: 207, line: "ENABLE_HOST_CHECK;db01"},
+		{name: "bulk", path: "/bulk_command?dry_run=true", body: `{"filter": {"object": "host", "host_name": "web01"}, "command": "ENABLE_HOST_CHECK"}`, code: 200, line: "ENABLE_HOST_CHECK;web01"},
+		{name: "nrdp", path: "/nrdp/?dry_run=true&token=secret&cmd=submitcheck&JSONDATA=" + `{"checkresults":[{"checkresult":{"type":"host"},"hostname":"web01","state":"0","output":"up"}]}`, code: 200, line: "PROCESS_HOST_CHECK_RESULT;web01;0;up"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			api := newBulkTestApi(t)
+			api.dryRunMode = tt.global
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, strings.Replace(tt.path, `"`, "%22", -1), strings.NewReader(tt.body))
+			api.router.ServeHTTP(w, r)
+
+			is.Equal(w.Code, tt.code)
+			is.Equal(len(writtenCommands(t, api)), 0)
+			if tt.line != "" {
+				is.True(regexp.MustCompile(`\[\d+\] ` + regexp.QuoteMeta(tt.line)).MatchString(w.Body.String()))
+			}
+		})
+	}
+}
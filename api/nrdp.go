@@ -0,0 +1,225 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Sebor/nagios-api/auth"
+)
+
+// NRDP compatible receiver, so send_nrdp clients can submit passive checks directly.
+// See https://github.com/NagiosEnterprises/nrdp
+
+type nrdpCheckResult struct {
+	Type        string
+	Hostname    string
+	ServiceName string
+	State       string
+	Output      string
+}
+
+type nrdpXMLCheckResults struct {
+	CheckResults []struct {
+		Type        string `xml:"type,attr"`
+		Hostname    string `xml:"hostname"`
+		ServiceName string `xml:"servicename"`
+		State       string `xml:"state"`
+		Output      string `xml:"output"`
+	} `xml:"checkresult"`
+}
+
+type nrdpJSONCheckResults struct {
+	CheckResults []struct {
+		CheckResult struct {
+			Type string `json:"type"`
+		} `json:"checkresult"`
+		Hostname    string      `json:"hostname"`
+		ServiceName string      `json:"servicename"`
+		State       json.Number `json:"state"`
+		Output      string      `json:"output"`
+	} `json:"checkresults"`
+}
+
+type nrdpResult struct {
+	XMLName xml.Name `xml:"result" json:"-"`
+	Status  int      `xml:"status" json:"status"`
+	Message string   `xml:"message" json:"message"`
+	Meta    *struct {
+		Output string `xml:"output" json:"output"`
+	} `xml:"meta,omitempty" json:"meta,omitempty"`
+}
+
+func parseNrdpXML(data string) ([]nrdpCheckResult, error) {
+	var doc nrdpXMLCheckResults
+	if err := xml.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, err
+	}
+
+	var results []nrdpCheckResult
+	for _, item := range doc.CheckResults {
+		results = append(results, nrdpCheckResult{Type: item.Type, Hostname: item.Hostname, ServiceName: item.ServiceName, State: item.State, Output: item.Output})
+	}
+	return results, nil
+}
+
+func parseNrdpJSON(data string) ([]nrdpCheckResult, error) {
+	var doc nrdpJSONCheckResults
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var results []nrdpCheckResult
+	for _, item := range doc.CheckResults {
+		results = append(results, nrdpCheckResult{Type: item.CheckResult.Type, Hostname: item.Hostname, ServiceName: item.ServiceName, State: item.State.String(), Output: item.Output})
+	}
+	return results, nil
+}
+
+// passiveCheckResult converts an NRDP check result into the passive result written to the command file
+func (c nrdpCheckResult) passiveCheckResult() (passiveCheckResult, error) {
+	result := passiveCheckResult{Hostname: c.Hostname, Output: c.Output}
+	if c.Hostname == "" {
+		return result, fmt.Errorf("missing hostname")
+	}
+
+	state, err := strconv.Atoi(strings.TrimSpace(c.State))
+	if err != nil {
+		return result, fmt.Errorf("invalid state %q for %s", c.State, c.Hostname)
+	}
+	result.ReturnCode = &state
+
+	switch strings.ToLower(c.Type) {
+	case "host":
+		if state < 0 || state > 2 {
+			return result, fmt.Errorf("invalid host state %d for %s", state, c.Hostname)
+		}
+	case "service":
+		if c.ServiceName == "" {
+			return result, fmt.Errorf("missing servicename for %s", c.Hostname)
+		}
+		if state < 0 || state > 3 {
+			return result, fmt.Errorf("invalid service state %d for %s;%s", state, c.Hostname, c.ServiceName)
+		}
+		result.ServiceDescription = c.ServiceName
+	default:
+		return result, fmt.Errorf("invalid check result type %q for %s", c.Type, c.Hostname)
+	}
+	return result, nil
+}
+
+func (a *Api) validNrdpToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	for _, t := range a.nrdpTokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNrdpResult replies in the format NRDP clients expect, JSON when the data was submitted as JSON
+func writeNrdpResult(w http.ResponseWriter, asJSON bool, status int, message, output string) {
+	result := nrdpResult{Status: status, Message: message}
+	if output != "" {
+		result.Meta = &struct {
+			Output string `xml:"output" json:"output"`
+		}{Output: output}
+	}
+
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]nrdpResult{"result": result})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(result)
+}
+
+// HandleNrdp accepts NRDP submitcheck requests
+// POST: /nrdp/
+//       token=<token>&cmd=submitcheck&XMLDATA=<checkresults> or JSONDATA=<checkresults>
+func (a *Api) HandleNrdp(w http.ResponseWriter, r *http.Request) {
+	jsonData := r.FormValue("JSONDATA")
+	asJSON := jsonData != ""
+
+	if !a.validNrdpToken(r.FormValue("token")) {
+		writeNrdpResult(w, asJSON, -1, "BAD TOKEN", "")
+		return
+	}
+	r = auth.WithPrincipal(r, "nrdp")
+
+	if r.FormValue("cmd") != "submitcheck" {
+		writeNrdpResult(w, asJSON, -1, "NO COMMAND SPECIFIED", "")
+		return
+	}
+
+	var results []nrdpCheckResult
+	var err error
+	switch {
+	case asJSON:
+		results, err = parseNrdpJSON(jsonData)
+	case r.FormValue("XMLDATA") != "":
+		results, err = parseNrdpXML(r.FormValue("XMLDATA"))
+	default:
+		writeNrdpResult(w, asJSON, -1, "NO DATA", "")
+		return
+	}
+	if err != nil {
+		writeNrdpResult(w, asJSON, -1, "BAD DATA", err.Error())
+		return
+	}
+	if len(results) == 0 {
+		writeNrdpResult(w, asJSON, -1, "NO DATA", "")
+		return
+	}
+
+	snap := a.snapshotOf(r)
+	var commands []string
+	for _, item := range results {
+		result, err := item.passiveCheckResult()
+		if err != nil {
+			writeNrdpResult(w, asJSON, -1, "BAD DATA", err.Error())
+			return
+		}
+		if result.ServiceDescription == "" && !snap.static.hasHost(result.Hostname) {
+			writeNrdpResult(w, asJSON, -1, "BAD DATA", fmt.Sprintf("unknown host %s", result.Hostname))
+			return
+		}
+		if result.ServiceDescription != "" && !snap.static.hasService(result.Hostname, result.ServiceDescription) {
+			writeNrdpResult(w, asJSON, -1, "BAD DATA", fmt.Sprintf("unknown service %s on host %s", result.ServiceDescription, result.Hostname))
+			return
+		}
+		command, err := result.command()
+		if err != nil {
+			writeNrdpResult(w, asJSON, -1, "BAD DATA", err.Error())
+			return
+		}
+		commands = append(commands, command)
+	}
+
+	dryRun, err := a.submitCommands(r, commands)
+	if _, ok := err.(forbiddenError); ok {
+		writeNrdpResult(w, asJSON, -1, "NOT AUTHORIZED", err.Error())
+		return
+	} else if err != nil {
+		writeNrdpResult(w, asJSON, -1, "UNABLE TO WRITE TO COMMAND FILE", "")
+		return
+	}
+	if dryRun {
+		writeNrdpResult(w, asJSON, 0, "OK", fmt.Sprintf("%d checks validated, dry run:\n%s", len(commands), strings.Join(commandLines(commands), "\n")))
+		return
+	}
+
+	writeNrdpResult(w, asJSON, 0, "OK", fmt.Sprintf("%d checks processed.", len(commands)))
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+)
+
+func TestHandleNrdp(t *testing.T) {
+	xmlData := `<?xml version='1.0'?>
+<checkresults>
+  <checkresult type='host' checktype='1'>
+    <hostname>web01</hostname>
+    <state>0</state>
+    <output>PING OK</output>
+  </checkresult>
+  <checkresult type='service' checktype='1'>
+    <hostname>web01</hostname>
+    <servicename>HTTP</servicename>
+    <state>1</state>
+    <output>HTTP WARNING|time=2s</output>
+  </checkresult>
+</checkresults>`
+	jsonData := `{"checkresults": [{"checkresult": {"type": "service", "checktype": "1"}, "hostname": "db01", "servicename": "MySQL", "state": "2", "output": "down"}]}`
+
+	tests := []struct {
+		name     string
+		form     url.Values
+		contains string
+		commands []string
+	}{
+		{
+			name:     "xml",
+			form:     url.Values{"token": {"secret"}, "cmd": {"submitcheck"}, "XMLDATA": {xmlData}},
+			contains: "<status>0</status><message>OK</message><meta><output>2 checks processed.</output></meta>",
+			commands: []string{
+				"PROCESS_HOST_CHECK_RESULT;web01;0;PING OK",
+				"PROCESS_SERVICE_CHECK_RESULT;web01;HTTP;1;HTTP WARNING|time=2s",
+			},
+		},
+		{
+			name:     "json",
+			form:     url.Values{"token": {"secret"}, "cmd": {"submitcheck"}, "JSONDATA": {jsonData}},
+			contains: `{"result":{"status":0,"message":"OK","meta":{"output":"1 checks processed."}}}`,
+			commands: []string{"PROCESS_SERVICE_CHECK_RESULT;db01;MySQL;2;down"},
+		},
+		{
+			name:     "bad token",
+			form:     url.Values{"token": {"wrong"}, "cmd": {"submitcheck"}, "XMLDATA": {xmlData}},
+			contains: "<message>BAD TOKEN</message>",
+		},
+		{
+			name:     "unknown host",
+			form:     url.Values{"token": {"secret"}, "cmd": {"submitcheck"}, "XMLDATA": {strings.Replace(xmlData, "<hostname>web01</hostname>\n    <state>0", "<hostname>web1</hostname>\n    <state>0", 1)}},
+			contains: "<message>BAD DATA</message><meta><output>unknown host web1</output></meta>",
+		},
+		{
+			name:     "unknown service",
+			form:     url.Values{"token": {"secret"}, "cmd": {"submitcheck"}, "JSONDATA": {strings.Replace(jsonData, "MySQL", "PostgreSQL", 1)}},
+			contains: `{"result":{"status":-1,"message":"BAD DATA","meta":{"output":"unknown service PostgreSQL on host db01"}}}`,
+		},
+		{
+			name:     "no data",
+			form:     url.Values{"token": {"secret"}, "cmd": {"submitcheck"}},
+			contains: "<message>NO DATA</message>",
+		},
+		{
+			name:     "no check results",
+			form:     url.Values{"token": {"secret"}, "cmd": {"submitcheck"}, "JSONDATA": {`{"checkresults": []}`}},
+			contains: `{"result":{"status":-1,"message":"NO DATA"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			api := newTestApi(t)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/nrdp/", strings.NewReader(tt.form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			api.router.ServeHTTP(w, r)
+
+			is.Equal(w.Code, 200)
+			is.True(strings.Contains(w.Body.String(), tt.contains))
+			is.Equal(strings.Join(writtenCommands(t, api), "\n"), strings.Join(tt.commands, "\n"))
+		})
+	}
+}
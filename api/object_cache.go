@@ -0,0 +1,432 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The object definitions nagios writes to objects.cache. Fields are set from the attribute named
+// by their JSON tag: lists from comma separated values, booleans from 0 and 1. Every attribute is
+// also kept as written in Attributes, which the unversioned API returns.
+
+// Command is a command definition, used for checks, notifications and event handlers
+type Command struct {
+	CommandName string            `json:"command_name"`
+	CommandLine string            `json:"command_line"`
+	Attributes  map[string]string `json:"-"`
+}
+
+// Timeperiod is a timeperiod definition. Ranges holds the time ranges by weekday or date
+// exception, e.g. "monday" or "december 25".
+type Timeperiod struct {
+	TimeperiodName string            `json:"timeperiod_name"`
+	Alias          string            `json:"alias"`
+	Exclude        []string          `json:"exclude,omitempty"`
+	Ranges         map[string]string `json:"ranges,omitempty"`
+	Attributes     map[string]string `json:"-"`
+}
+
+// Contact is a contact definition
+type Contact struct {
+	ContactName                 string            `json:"contact_name"`
+	Alias                       string            `json:"alias"`
+	Contactgroups               []string          `json:"contactgroups,omitempty"`
+	Email                       string            `json:"email"`
+	Pager                       string            `json:"pager"`
+	HostNotificationPeriod      string            `json:"host_notification_period"`
+	ServiceNotificationPeriod   string            `json:"service_notification_period"`
+	HostNotificationOptions     []string          `json:"host_notification_options,omitempty"`
+	ServiceNotificationOptions  []string          `json:"service_notification_options,omitempty"`
+	HostNotificationCommands    []string          `json:"host_notification_commands,omitempty"`
+	ServiceNotificationCommands []string          `json:"service_notification_commands,omitempty"`
+	HostNotificationsEnabled    bool              `json:"host_notifications_enabled"`
+	ServiceNotificationsEnabled bool              `json:"service_notifications_enabled"`
+	CanSubmitCommands           bool              `json:"can_submit_commands"`
+	RetainStatusInformation     bool              `json:"retain_status_information"`
+	RetainNonstatusInformation  bool              `json:"retain_nonstatus_information"`
+	MinimumImportance           int               `json:"minimum_importance"`
+	CustomVariables             map[string]string `json:"custom_variables,omitempty"`
+	Attributes                  map[string]string `json:"-"`
+}
+
+// Contactgroup is a contactgroup definition
+type Contactgroup struct {
+	ContactgroupName string            `json:"contactgroup_name"`
+	Alias            string            `json:"alias"`
+	Members          []string          `json:"members,omitempty"`
+	Attributes       map[string]string `json:"-"`
+}
+
+// Host is a host definition
+type Host struct {
+	HostName                   string            `json:"host_name"`
+	DisplayName                string            `json:"display_name"`
+	Alias                      string            `json:"alias"`
+	Address                    string            `json:"address"`
+	Parents                    []string          `json:"parents,omitempty"`
+	Hostgroups                 []string          `json:"hostgroups,omitempty"`
+	CheckPeriod                string            `json:"check_period"`
+	CheckCommand               string            `json:"check_command"`
+	EventHandler               string            `json:"event_handler"`
+	Contacts                   []string          `json:"contacts,omitempty"`
+	ContactGroups              []string          `json:"contact_groups,omitempty"`
+	NotificationPeriod         string            `json:"notification_period"`
+	InitialState               string            `json:"initial_state"`
+	Importance                 int               `json:"importance"`
+	CheckInterval              float64           `json:"check_interval"`
+	RetryInterval              float64           `json:"retry_interval"`
+	MaxCheckAttempts           int               `json:"max_check_attempts"`
+	ActiveChecksEnabled        bool              `json:"active_checks_enabled"`
+	PassiveChecksEnabled       bool              `json:"passive_checks_enabled"`
+	Obsess                     bool              `json:"obsess"`
+	EventHandlerEnabled        bool              `json:"event_handler_enabled"`
+	LowFlapThreshold           float64           `json:"low_flap_threshold"`
+	HighFlapThreshold          float64           `json:"high_flap_threshold"`
+	FlapDetectionEnabled       bool              `json:"flap_detection_enabled"`
+	FlapDetectionOptions       []string          `json:"flap_detection_options,omitempty"`
+	FreshnessThreshold         int               `json:"freshness_threshold"`
+	CheckFreshness             bool              `json:"check_freshness"`
+	NotificationOptions        []string          `json:"notification_options,omitempty"`
+	NotificationsEnabled       bool              `json:"notifications_enabled"`
+	NotificationInterval       float64           `json:"notification_interval"`
+	FirstNotificationDelay     float64           `json:"first_notification_delay"`
+	StalkingOptions            []string          `json:"stalking_options,omitempty"`
+	ProcessPerfData            bool              `json:"process_perf_data"`
+	Notes                      string            `json:"notes"`
+	NotesURL                   string            `json:"notes_url"`
+	ActionURL                  string            `json:"action_url"`
+	IconImage                  string            `json:"icon_image"`
+	IconImageAlt               string            `json:"icon_image_alt"`
+	VrmlImage                  string            `json:"vrml_image"`
+	StatusmapImage             string            `json:"statusmap_image"`
+	Coords2D                   string            `json:"2d_coords"`
+	Coords3D                   string            `json:"3d_coords"`
+	RetainStatusInformation    bool              `json:"retain_status_information"`
+	RetainNonstatusInformation bool              `json:"retain_nonstatus_information"`
+	CustomVariables            map[string]string `json:"custom_variables,omitempty"`
+	Attributes                 map[string]string `json:"-"`
+}
+
+// Hostgroup is a hostgroup definition
+type Hostgroup struct {
+	HostgroupName string            `json:"hostgroup_name"`
+	Alias         string            `json:"alias"`
+	Members       []string          `json:"members,omitempty"`
+	Notes         string            `json:"notes"`
+	NotesURL      string            `json:"notes_url"`
+	ActionURL     string            `json:"action_url"`
+	Attributes    map[string]string `json:"-"`
+}
+
+// Service is a service definition
+type Service struct {
+	HostName                   string            `json:"host_name"`
+	ServiceDescription         string            `json:"service_description"`
+	DisplayName                string            `json:"display_name"`
+	Parents                    []string          `json:"parents,omitempty"`
+	Servicegroups              []string          `json:"servicegroups,omitempty"`
+	CheckPeriod                string            `json:"check_period"`
+	CheckCommand               string            `json:"check_command"`
+	EventHandler               string            `json:"event_handler"`
+	Contacts                   []string          `json:"contacts,omitempty"`
+	ContactGroups              []string          `json:"contact_groups,omitempty"`
+	NotificationPeriod         string            `json:"notification_period"`
+	InitialState               string            `json:"initial_state"`
+	Importance                 int               `json:"importance"`
+	CheckInterval              float64           `json:"check_interval"`
+	RetryInterval              float64           `json:"retry_interval"`
+	MaxCheckAttempts           int               `json:"max_check_attempts"`
+	IsVolatile                 bool              `json:"is_volatile"`
+	ParallelizeCheck           bool              `json:"parallelize_check"`
+	ActiveChecksEnabled        bool              `json:"active_checks_enabled"`
+	PassiveChecksEnabled       bool              `json:"passive_checks_enabled"`
+	Obsess                     bool              `json:"obsess"`
+	EventHandlerEnabled        bool              `json:"event_handler_enabled"`
+	LowFlapThreshold           float64           `json:"low_flap_threshold"`
+	HighFlapThreshold          float64           `json:"high_flap_threshold"`
+	FlapDetectionEnabled       bool              `json:"flap_detection_enabled"`
+	FlapDetectionOptions       []string          `json:"flap_detection_options,omitempty"`
+	FreshnessThreshold         int               `json:"freshness_threshold"`
+	CheckFreshness             bool              `json:"check_freshness"`
+	NotificationOptions        []string          `json:"notification_options,omitempty"`
+	NotificationsEnabled       bool              `json:"notifications_enabled"`
+	NotificationInterval       float64           `json:"notification_interval"`
+	FirstNotificationDelay     float64           `json:"first_notification_delay"`
+	StalkingOptions            []string          `json:"stalking_options,omitempty"`
+	ProcessPerfData            bool              `json:"process_perf_data"`
+	Notes                      string            `json:"notes"`
+	NotesURL                   string            `json:"notes_url"`
+	ActionURL                  string            `json:"action_url"`
+	IconImage                  string            `json:"icon_image"`
+	IconImageAlt               string            `json:"icon_image_alt"`
+	RetainStatusInformation    bool              `json:"retain_status_information"`
+	RetainNonstatusInformation bool              `json:"retain_nonstatus_information"`
+	CustomVariables            map[string]string `json:"custom_variables,omitempty"`
+	Attributes                 map[string]string `json:"-"`
+}
+
+// Servicegroup is a servicegroup definition. Members alternate host names and service
+// descriptions.
+type Servicegroup struct {
+	ServicegroupName string            `json:"servicegroup_name"`
+	Alias            string            `json:"alias"`
+	Members          []string          `json:"members,omitempty"`
+	Notes            string            `json:"notes"`
+	NotesURL         string            `json:"notes_url"`
+	ActionURL        string            `json:"action_url"`
+	Attributes       map[string]string `json:"-"`
+}
+
+// HostDependency is a hostdependency definition
+type HostDependency struct {
+	HostName                   string            `json:"host_name"`
+	DependentHostName          string            `json:"dependent_host_name"`
+	DependencyPeriod           string            `json:"dependency_period"`
+	InheritsParent             bool              `json:"inherits_parent"`
+	NotificationFailureOptions []string          `json:"notification_failure_options,omitempty"`
+	ExecutionFailureOptions    []string          `json:"execution_failure_options,omitempty"`
+	Attributes                 map[string]string `json:"-"`
+}
+
+// ServiceDependency is a servicedependency definition
+type ServiceDependency struct {
+	HostName                    string            `json:"host_name"`
+	ServiceDescription          string            `json:"service_description"`
+	DependentHostName           string            `json:"dependent_host_name"`
+	DependentServiceDescription string            `json:"dependent_service_description"`
+	DependencyPeriod            string            `json:"dependency_period"`
+	InheritsParent              bool              `json:"inherits_parent"`
+	NotificationFailureOptions  []string          `json:"notification_failure_options,omitempty"`
+	ExecutionFailureOptions     []string          `json:"execution_failure_options,omitempty"`
+	Attributes                  map[string]string `json:"-"`
+}
+
+// HostEscalation is a hostescalation definition
+type HostEscalation struct {
+	HostName             string            `json:"host_name"`
+	Contacts             []string          `json:"contacts,omitempty"`
+	ContactGroups        []string          `json:"contact_groups,omitempty"`
+	FirstNotification    int               `json:"first_notification"`
+	LastNotification     int               `json:"last_notification"`
+	NotificationInterval float64           `json:"notification_interval"`
+	EscalationPeriod     string            `json:"escalation_period"`
+	EscalationOptions    []string          `json:"escalation_options,omitempty"`
+	Attributes           map[string]string `json:"-"`
+}
+
+// ServiceEscalation is a serviceescalation definition
+type ServiceEscalation struct {
+	HostName             string            `json:"host_name"`
+	ServiceDescription   string            `json:"service_description"`
+	Contacts             []string          `json:"contacts,omitempty"`
+	ContactGroups        []string          `json:"contact_groups,omitempty"`
+	FirstNotification    int               `json:"first_notification"`
+	LastNotification     int               `json:"last_notification"`
+	NotificationInterval float64           `json:"notification_interval"`
+	EscalationPeriod     string            `json:"escalation_period"`
+	EscalationOptions    []string          `json:"escalation_options,omitempty"`
+	Attributes           map[string]string `json:"-"`
+}
+
+// newObject returns a new definition for a define block of type kind, added to d, or nil when
+// kind is not known
+func (d *StaticData) newObject(kind string) interface{} {
+	switch kind {
+	case "command":
+		obj := &Command{}
+		d.Commands = append(d.Commands, obj)
+		return obj
+	case "timeperiod":
+		obj := &Timeperiod{}
+		d.Timeperiods = append(d.Timeperiods, obj)
+		return obj
+	case "contact":
+		obj := &Contact{}
+		d.Contacts = append(d.Contacts, obj)
+		return obj
+	case "contactgroup":
+		obj := &Contactgroup{}
+		d.Contactgroups = append(d.Contactgroups, obj)
+		return obj
+	case "host":
+		obj := &Host{}
+		d.Hosts = append(d.Hosts, obj)
+		return obj
+	case "hostgroup":
+		obj := &Hostgroup{}
+		d.Hostgroups = append(d.Hostgroups, obj)
+		return obj
+	case "service":
+		obj := &Service{}
+		d.Services = append(d.Services, obj)
+		return obj
+	case "servicegroup":
+		obj := &Servicegroup{}
+		d.Servicegroups = append(d.Servicegroups, obj)
+		return obj
+	case "hostdependency":
+		obj := &HostDependency{}
+		d.HostDependencies = append(d.HostDependencies, obj)
+		return obj
+	case "servicedependency":
+		obj := &ServiceDependency{}
+		d.ServiceDependencies = append(d.ServiceDependencies, obj)
+		return obj
+	case "hostescalation":
+		obj := &HostEscalation{}
+		d.HostEscalations = append(d.HostEscalations, obj)
+		return obj
+	case "serviceescalation":
+		obj := &ServiceEscalation{}
+		d.ServiceEscalations = append(d.ServiceEscalations, obj)
+		return obj
+	}
+	return nil
+}
+
+// objectFields caches the field index of each attribute by definition type
+var objectFields sync.Map
+
+func fieldIndexes(typ reflect.Type) map[string]int {
+	if fields, ok := objectFields.Load(typ); ok {
+		return fields.(map[string]int)
+	}
+	fields := make(map[string]int)
+	for i := 0; i < typ.NumField(); i++ {
+		if js := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]; js != "" && js != "-" {
+			fields[js] = i
+		}
+	}
+	objectFields.Store(typ, fields)
+	return fields
+}
+
+// setAttribute sets the attribute key of the definition obj. Custom variables and the time ranges
+// of timeperiods go to their maps, attributes without a field are only kept in Attributes.
+func setAttribute(obj interface{}, key, value string) error {
+	val := reflect.ValueOf(obj).Elem()
+	setMapEntry(val.FieldByName("Attributes"), key, value)
+
+	if strings.HasPrefix(key, "_") {
+		setMapEntry(val.FieldByName("CustomVariables"), strings.TrimPrefix(key, "_"), value)
+		return nil
+	}
+	i, ok := fieldIndexes(val.Type())[key]
+	if !ok {
+		setMapEntry(val.FieldByName("Ranges"), key, value)
+		return nil
+	}
+
+	field := val.Field(i)
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s is not a number: %q", key, value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s is not a number: %q", key, value)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		if value != "0" && value != "1" {
+			return fmt.Errorf("%s is not 0 or 1: %q", key, value)
+		}
+		field.SetBool(value == "1")
+	case reflect.Slice:
+		field.Set(reflect.ValueOf(splitList(value)))
+	}
+	return nil
+}
+
+// setMapEntry sets key in the map field, when the definition has that field
+func setMapEntry(field reflect.Value, key, value string) {
+	if !field.IsValid() {
+		return
+	}
+	if field.IsNil() {
+		field.Set(reflect.ValueOf(map[string]string{}))
+	}
+	field.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(value))
+}
+
+// splitAttribute splits a definition line into attribute and value. Nagios separates them with
+// a tab, which lets timeperiod exceptions such as "december 25" hold spaces. Hand edited files
+// may use spaces instead.
+func splitAttribute(line string) (string, string) {
+	sep := strings.IndexByte(line, '\t')
+	if sep == -1 {
+		sep = strings.IndexAny(line, " ")
+	}
+	if sep == -1 {
+		return line, ""
+	}
+	return strings.TrimSpace(line[:sep]), strings.TrimSpace(line[sep+1:])
+}
+
+func readObjectCache(in io.Reader) (*StaticData, error) {
+	data := NewStaticData()
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxStatusLine)
+
+	var (
+		lineNumber int
+		kind       string
+		// obj is nil inside define blocks of unknown types
+		obj     interface{}
+		inBlock bool
+	)
+	errorf := func(format string, args ...interface{}) error {
+		return fmt.Errorf("objects.cache line %d: %s", lineNumber, fmt.Sprintf(format, args...))
+	}
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if !inBlock {
+			fields := strings.Fields(strings.TrimSuffix(line, "{"))
+			if len(fields) != 2 || fields[0] != "define" || !strings.HasSuffix(line, "{") {
+				return nil, errorf("expected define <type> {, got %.80q", line)
+			}
+			kind, inBlock = fields[1], true
+			obj = data.newObject(kind)
+			continue
+		}
+
+		if line == "}" {
+			inBlock, obj = false, nil
+			continue
+		}
+
+		key, value := splitAttribute(line)
+		if obj == nil {
+			continue
+		}
+		if err := setAttribute(obj, key, value); err != nil {
+			return nil, errorf("%s in %s definition", err, kind)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if inBlock {
+		return nil, errorf("unexpected end of file in %s definition", kind)
+	}
+	data.index()
+	return data, nil
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+)
+
+func readObjectCacheFile(t *testing.T, path string) *StaticData {
+	fh, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+
+	data, err := readObjectCache(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReadObjectCache(t *testing.T) {
+	is := is.New(t)
+	data := readObjectCacheFile(t, "testdata/objects_full.cache")
+
+	is.Equal(len(data.Timeperiods), 2)
+	tp := data.Timeperiods[0]
+	is.Equal(tp.TimeperiodName, "24x7")
+	is.Equal(tp.Exclude, []string{"holidays"})
+	is.Equal(tp.Ranges["monday"], "00:00-24:00")
+	is.Equal(tp.Ranges["december 25"], "00:00-00:00")
+	is.Equal(len(tp.Ranges), 4)
+
+	is.Equal(len(data.Commands), 2)
+	is.Equal(data.Commands[0].CommandLine, "$USER1$/check_http -I $HOSTADDRESS$ $ARG1$")
+	is.True(strings.HasSuffix(data.Commands[1].CommandLine, "| /usr/bin/mail -s \"$HOSTNAME$ is $HOSTSTATE$\" $CONTACTEMAIL$"))
+
+	is.Equal(len(data.Contactgroups), 1)
+	is.Equal(data.Contactgroups[0].Members, []string{"jason", "alice"})
+	is.Equal(data.Hostgroups[0].Members, []string{"web01", "web02"})
+	is.Equal(data.Hostgroups[0].NotesURL, "https://wiki.example.com/web")
+	is.Equal(data.Servicegroups[0].Members, []string{"web01", "HTTP", "web02", "HTTP"})
+
+	is.Equal(len(data.Contacts), 1)
+	c := data.Contacts[0]
+	is.Equal(c.Contactgroups, []string{"admins"})
+	is.Equal(c.ServiceNotificationOptions, []string{"w", "u", "c", "r", "f", "s"})
+	is.True(c.HostNotificationsEnabled)
+	is.False(c.ServiceNotificationsEnabled)
+	is.Equal(c.CustomVariables, map[string]string{"PAGERDUTY_KEY": "abc123"})
+
+	is.Equal(len(data.Hosts), 1)
+	h := data.Hosts[0]
+	is.Equal(h.Parents, []string{"router01"})
+	is.Equal(h.ContactGroups, []string{"admins"})
+	is.Equal(h.MaxCheckAttempts, 10)
+	is.Equal(h.CheckInterval, 5.0)
+	is.Equal(h.NotificationInterval, 120.0)
+	is.True(h.ActiveChecksEnabled)
+	is.False(h.CheckFreshness)
+	is.Equal(h.Coords2D, "100,250")
+	is.Equal(h.CustomVariables["RACK"], "B4")
+	// Attributes keep every value as written for the unversioned API
+	is.Equal(h.Attributes["check_interval"], "5.000000")
+	is.Equal(h.Attributes["_RACK"], "B4")
+
+	is.Equal(len(data.Services), 1)
+	s := data.Services[0]
+	is.Equal(s.CheckCommand, "check_http!-u /health")
+	is.Equal(s.Contacts, []string{"alice"})
+	is.Equal(s.MaxCheckAttempts, 3)
+	is.True(s.ParallelizeCheck)
+	is.Equal(s.Notes, "Served by nginx")
+
+	is.Equal(len(data.ServiceDependencies), 1)
+	sd := data.ServiceDependencies[0]
+	is.Equal(sd.DependentServiceDescription, "HTTP")
+	is.True(sd.InheritsParent)
+	is.Equal(sd.NotificationFailureOptions, []string{"w", "u", "c"})
+
+	is.Equal(len(data.HostDependencies), 1)
+	is.Equal(data.HostDependencies[0].ExecutionFailureOptions, []string{"d", "u"})
+	is.False(data.HostDependencies[0].InheritsParent)
+
+	is.Equal(len(data.ServiceEscalations), 1)
+	is.Equal(data.ServiceEscalations[0].FirstNotification, 3)
+	is.Equal(data.ServiceEscalations[0].NotificationInterval, 30.0)
+	is.Equal(len(data.HostEscalations), 1)
+	is.Equal(data.HostEscalations[0].Contacts, []string{"jason"})
+	is.Equal(data.HostEscalations[0].LastNotification, 5)
+}
+
+func TestReadObjectCacheWhitespace(t *testing.T) {
+	is := is.New(t)
+	data := readObjectCacheFile(t, "testdata/objects_whitespace.cache")
+
+	is.Equal(len(data.Hosts), 1)
+	is.Equal(data.Hosts[0].HostName, "web01")
+	is.Equal(data.Hosts[0].Alias, "web01  example")
+	is.Equal(data.Hosts[0].MaxCheckAttempts, 10)
+	is.Equal(data.Hosts[0].Contacts, []string{"jason", "alice"})
+
+	is.Equal(len(data.Services), 1)
+	is.Equal(data.Services[0].HostName, "web01")
+	is.Equal(data.Services[0].ServiceDescription, "Disk Usage /var")
+	is.Equal(data.Services[0].CheckInterval, 2.5)
+
+	is.Equal(data.Timeperiods[0].Ranges, map[string]string{"monday": "09:00-17:00"})
+}
+
+func TestReadObjectCacheErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{name: "attribute outside definition", input: "define host {\n}\nhost_name\tweb01\n", err: `objects.cache line 3: expected define <type> {, got "host_name\tweb01"`},
+		{name: "missing brace", input: "define host\n\thost_name\tweb01\n}\n", err: `objects.cache line 1: expected define <type> {, got "define host"`},
+		{name: "invalid number", input: "define host {\n\thost_name\tweb01\n\tmax_check_attempts\tten\n}\n", err: `objects.cache line 3: max_check_attempts is not a number: "ten" in host definition`},
+		{name: "invalid boolean", input: "\ndefine service {\n\tis_volatile\tyes\n}\n", err: `objects.cache line 3: is_volatile is not 0 or 1: "yes" in service definition`},
+		{name: "truncated", input: "define contact {\n\tcontact_name\tjason\n", err: "objects.cache line 2: unexpected end of file in contact definition"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			_, err := readObjectCache(strings.NewReader(tt.input))
+			is.Err(err)
+			is.Equal(err.Error(), tt.err)
+		})
+	}
+}
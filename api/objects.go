@@ -78,6 +78,16 @@ type HostStatus struct {
 	CustomVariables            map[string]string `json:"custom_variables,omitempty"`
 }
 
+// InfoStatus struct
+type InfoStatus struct {
+	Created         string `json:"created"`
+	Version         string `json:"version"`
+	LastUpdateCheck string `json:"last_update_check"`
+	UpdateAvailable string `json:"update_available"`
+	LastVersion     string `json:"last_version"`
+	NewVersion      string `json:"new_version"`
+}
+
 // ProgramStatus struct
 type ProgramStatus struct {
 	ActiveHostChecksEnabled          string            `json:"active_host_checks_enabled"`
@@ -177,20 +187,106 @@ type ServiceStatus struct {
 	CustomVariables            map[string]string `json:"custom_variables,omitempty"`
 }
 
+// HostComment struct
+type HostComment struct {
+	HostName    string `json:"host_name"`
+	EntryType   string `json:"entry_type"`
+	CommentID   string `json:"comment_id"`
+	Source      string `json:"source"`
+	Persistent  string `json:"persistent"`
+	EntryTime   string `json:"entry_time"`
+	Expires     string `json:"expires"`
+	ExpireTime  string `json:"expire_time"`
+	Author      string `json:"author"`
+	CommentData string `json:"comment_data"`
+}
+
+// ServiceComment struct
+type ServiceComment struct {
+	HostName           string `json:"host_name"`
+	ServiceDescription string `json:"service_description"`
+	EntryType          string `json:"entry_type"`
+	CommentID          string `json:"comment_id"`
+	Source             string `json:"source"`
+	Persistent         string `json:"persistent"`
+	EntryTime          string `json:"entry_time"`
+	Expires            string `json:"expires"`
+	ExpireTime         string `json:"expire_time"`
+	Author             string `json:"author"`
+	CommentData        string `json:"comment_data"`
+}
+
+// HostDowntime struct
+type HostDowntime struct {
+	HostName              string `json:"host_name"`
+	DowntimeID            string `json:"downtime_id"`
+	CommentID             string `json:"comment_id"`
+	EntryTime             string `json:"entry_time"`
+	StartTime             string `json:"start_time"`
+	FlexDowntimeStart     string `json:"flex_downtime_start"`
+	EndTime               string `json:"end_time"`
+	TriggeredBy           string `json:"triggered_by"`
+	Fixed                 string `json:"fixed"`
+	Duration              string `json:"duration"`
+	IsInEffect            string `json:"is_in_effect"`
+	StartNotificationSent string `json:"start_notification_sent"`
+	Author                string `json:"author"`
+	Comment               string `json:"comment"`
+}
+
+// ServiceDowntime struct
+type ServiceDowntime struct {
+	HostName              string `json:"host_name"`
+	ServiceDescription    string `json:"service_description"`
+	DowntimeID            string `json:"downtime_id"`
+	CommentID             string `json:"comment_id"`
+	EntryTime             string `json:"entry_time"`
+	StartTime             string `json:"start_time"`
+	FlexDowntimeStart     string `json:"flex_downtime_start"`
+	EndTime               string `json:"end_time"`
+	TriggeredBy           string `json:"triggered_by"`
+	Fixed                 string `json:"fixed"`
+	Duration              string `json:"duration"`
+	IsInEffect            string `json:"is_in_effect"`
+	StartNotificationSent string `json:"start_notification_sent"`
+	Author                string `json:"author"`
+	Comment               string `json:"comment"`
+}
+
 func (o *ContactStatus) setField(key, value string) error {
-	return setField(o, key, value)
+	return setFieldRef(o.fieldRef(key), key, value)
 }
 
 func (o *HostStatus) setField(key, value string) error {
-	return setField(o, key, value)
+	return setFieldRef(o.fieldRef(key), key, value)
+}
+
+func (o *InfoStatus) setField(key, value string) error {
+	return setFieldRef(o.fieldRef(key), key, value)
 }
 
 func (o *ProgramStatus) setField(key, value string) error {
-	return setField(o, key, value)
+	return setFieldRef(o.fieldRef(key), key, value)
 }
 
 func (o *ServiceStatus) setField(key, value string) error {
-	return setField(o, key, value)
+	return setFieldRef(o.fieldRef(key), key, value)
+}
+
+func (o *HostComment) setField(key, value string) error {
+	return setFieldRef(o.fieldRef(key), key, value)
+}
+
+func (o *ServiceComment) setField(key, value string) error {
+	return setFieldRef(o.fieldRef(key), key, value)
+}
+
+func (o *HostDowntime) setField(key, value string) error {
+	return setFieldRef(o.fieldRef(key), key, value)
+}
+
+func (o *ServiceDowntime) setField(key, value string) error {
+	return setFieldRef(o.fieldRef(key), key, value)
 }
 
 func (o *ContactStatus) setCustomVariable(key, value string) {
@@ -221,6 +317,26 @@ func (o *ServiceStatus) setCustomVariable(key, value string) {
 	o.CustomVariables[key] = value
 }
 
+// Info, comments and downtimes never carry custom variables
+func (o *InfoStatus) setCustomVariable(key, value string) {}
+
+func (o *HostComment) setCustomVariable(key, value string) {}
+
+func (o *ServiceComment) setCustomVariable(key, value string) {}
+
+func (o *HostDowntime) setCustomVariable(key, value string) {}
+
+func (o *ServiceDowntime) setCustomVariable(key, value string) {}
+
+// setFieldRef sets the field returned by a fieldRef method
+func setFieldRef(field *string, key, value string) error {
+	if field == nil {
+		return fmt.Errorf("No such field: %s in obj", key)
+	}
+	*field = value
+	return nil
+}
+
 // setField sets a field in a struct based on the JSON tag associated with the struct
 func setField(obj interface{}, name string, value interface{}) error {
 	val := reflect.ValueOf(obj).Elem()
@@ -256,3 +372,24 @@ func setField(obj interface{}, name string, value interface{}) error {
 	}
 	return fmt.Errorf("No such field: %s in obj", name)
 }
+
+// getField returns the value of the string field of a struct with the given JSON tag
+func getField(obj interface{}, name string) (string, error) {
+	val := reflect.ValueOf(obj).Elem()
+
+	for i := 0; i < val.NumField(); i++ {
+		js := val.Type().Field(i).Tag.Get("json")
+		if comma := strings.Index(js, ","); comma != -1 {
+			js = js[0:comma]
+		}
+
+		if js == name {
+			field := val.Field(i)
+			if field.Kind() != reflect.String {
+				return "", fmt.Errorf("Field %s is not a string", name)
+			}
+			return field.String(), nil
+		}
+	}
+	return "", fmt.Errorf("No such field: %s in obj", name)
+}
@@ -1,8 +1,7 @@
 package api
 
 import (
-	"io/ioutil"
-	"strings"
+	"os"
 	"testing"
 
 	"github.com/cheekybits/is"
@@ -28,16 +27,14 @@ func TestSetField(t *testing.T) {
 func TestParseBlock(t *testing.T) {
 	is := is.New(t)
 
-	dat, err := ioutil.ReadFile("testdata/contact_status.dat")
+	fh, err := os.Open("testdata/contact_status.dat")
 	is.NoErr(err)
+	defer fh.Close()
 
-	a := strings.SplitAfterN(string(dat), "}", -1)
-	is.Equal(2, len(a))
-
-	lines := strings.Split(a[0], "\n")
-
-	c := &ContactStatus{}
-	parseBlock(c, "contactstatus", lines)
+	data, err := refreshStatusData(fh)
+	is.NoErr(err)
+	is.Equal(1, len(data.Contacts))
+	c := data.Contacts[0]
 
 	// Sample a handful of fields
 	is.Equal(c.ContactName, "jason")
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Default intervals of fileWatch. Nagios rewrites status.dat every status_update_interval,
+// 10 seconds by default.
+const (
+	defaultRefreshMin = 1 * time.Second
+	defaultRefreshMax = 60 * time.Second
+)
+
+// fileState identifies a version of a file
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// fileWatch reads a file again when it changes. Changes are noticed through file system
+// notifications and by checking its modification time and size every max interval, or every
+// min interval when notifications are not available. The file is read at most once per min
+// interval and only when it changed since it was read last.
+type fileWatch struct {
+	path string
+	min  time.Duration
+	max  time.Duration
+	// read is passed the modification time of the file it reads
+	read func(modTime time.Time) error
+	// polling is set when no file system notifications are received
+	polling  bool
+	last     fileState
+	lastRead time.Time
+}
+
+func newFileWatch(path string, min, max time.Duration, read func(modTime time.Time) error) *fileWatch {
+	if min <= 0 {
+		min = defaultRefreshMin
+	}
+	if max <= 0 {
+		max = defaultRefreshMax
+	}
+	if max < min {
+		max = min
+	}
+	return &fileWatch{path: path, min: min, max: max, read: read}
+}
+
+// check reads the file when it changed since it was read last
+func (f *fileWatch) check() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	state := fileState{modTime: info.ModTime(), size: info.Size()}
+	if state == f.last {
+		return nil
+	}
+
+	// An unchanged file is not read again, even when reading it failed
+	f.last, f.lastRead = state, time.Now()
+	return f.read(state.modTime)
+}
+
+// notifications returns a channel receiving a value when the file is written or replaced, and
+// a function to stop watching. The directory is watched as nagios replaces status.dat by
+// renaming a temporary file over it.
+func (f *fileWatch) notifications() (<-chan struct{}, func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := watcher.Add(filepath.Dir(f.path)); err != nil {
+		watcher.Close()
+		return nil, nil, err
+	}
+
+	changed := make(chan struct{}, 1)
+	name := filepath.Clean(f.path)
+	go func() {
+		defer close(changed)
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != name {
+					continue
+				}
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Println("Watching ", f.path, ": ", err)
+			}
+		}
+	}()
+	// Closing the watcher closes its channels, which ends the goroutine and closes changed
+	return changed, func() { watcher.Close() }, nil
+}
+
+// run checks the file until done is closed
+func (f *fileWatch) run(done <-chan struct{}) {
+	var changed <-chan struct{}
+	if !f.polling {
+		var stop func()
+		var err error
+		if changed, stop, err = f.notifications(); err != nil {
+			log.Println("Unable to watch ", f.path, ", polling instead: ", err)
+			f.polling = true
+		} else {
+			defer stop()
+		}
+	}
+	poll := f.max
+	if f.polling {
+		poll = f.min
+	}
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	// due fires when the file may be read again after a change was noticed
+	var due <-chan time.Time
+	schedule := func() {
+		if due == nil {
+			due = time.After(time.Until(f.lastRead.Add(f.min)))
+		}
+	}
+
+	schedule()
+	for {
+		select {
+		case <-done:
+			return
+		case <-changed:
+			schedule()
+		case <-ticker.C:
+			schedule()
+		case <-due:
+			due = nil
+			if err := f.check(); err != nil {
+				log.Println("Unable to refresh ", f.path, ": ", err)
+			}
+		}
+	}
+}
+
+// SetRefreshIntervals sets how often status.dat and objects.cache are read again: at most once
+// every min, and checked for changes at least every max. Zero keeps the default of 1 and 60 seconds.
+func (a *Api) SetRefreshIntervals(min, max time.Duration) {
+	a.refreshMin, a.refreshMax = min, max
+}
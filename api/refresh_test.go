@@ -0,0 +1,134 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+)
+
+// replaceFile writes content to path the way nagios does, by renaming a temporary file over it
+func replaceFile(t *testing.T, path, content string) {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileWatchCheck(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "nagios-api")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "status.dat")
+
+	reads := 0
+	f := newFileWatch(path, 0, 0, func(time.Time) error {
+		reads++
+		return nil
+	})
+	is.Equal(f.min, defaultRefreshMin)
+	is.Equal(f.max, defaultRefreshMax)
+
+	is.Err(f.check())
+	is.Equal(reads, 0)
+
+	replaceFile(t, path, "info {\n}\n")
+	is.NoErr(f.check())
+	is.NoErr(f.check())
+	is.Equal(reads, 1)
+
+	replaceFile(t, path, "info {\n    version=4.4.6\n}\n")
+	is.NoErr(f.check())
+	is.Equal(reads, 2)
+
+	// Only the modification time changed
+	later := time.Now().Add(time.Minute)
+	is.NoErr(os.Chtimes(path, later, later))
+	is.NoErr(f.check())
+	is.Equal(reads, 3)
+}
+
+func TestFileWatchRun(t *testing.T) {
+	for _, polling := range []bool{false, true} {
+		name := "notifications"
+		if polling {
+			name = "polling"
+		}
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+
+			dir, err := ioutil.TempDir("", "nagios-api")
+			is.NoErr(err)
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "status.dat")
+			replaceFile(t, path, "info {\n}\n")
+
+			reads := make(chan struct{}, 10)
+			// Without notifications the file is only checked every hour
+			f := newFileWatch(path, 200*time.Millisecond, time.Hour, func(time.Time) error {
+				reads <- struct{}{}
+				return nil
+			})
+			f.polling = polling
+			done := make(chan struct{})
+			defer close(done)
+			go f.run(done)
+
+			wait := func() {
+				select {
+				case <-reads:
+				case <-time.After(5 * time.Second):
+					t.Fatal("file was not read")
+				}
+			}
+			wait()
+
+			replaceFile(t, path, "info {\n    version=4.4.6\n}\n")
+			wait()
+
+			// Changes within the minimum interval are read together
+			replaceFile(t, path, "info {\n    version=4.4.7\n}\n")
+			replaceFile(t, path, "info {\n    version=4.4.8-rc1\n}\n")
+			wait()
+			select {
+			case <-reads:
+				t.Fatal("unchanged file was read again")
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestFileWatchStop(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "nagios-api")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+	f := newFileWatch(filepath.Join(dir, "status.dat"), 0, 0, nil)
+
+	changed, stop, err := f.notifications()
+	is.NoErr(err)
+	stop()
+
+	// The channel is closed once the watcher goroutine returned
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-changed:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("watcher was not stopped")
+		}
+	}
+}
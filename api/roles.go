@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/Sebor/nagios-api/auth"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+)
+
+type contextKey int
+
+const (
+	// routeGrantedKey marks requests whose route was granted by name, so the commands it issues
+	// need no permission of their own
+	routeGrantedKey contextKey = iota
+	// snapshotKey holds the snapshot a request is served from
+	snapshotKey
+)
+
+// roleConfig holds the permissions of each role. A permission is a route name, such as
+// acknowledge_host_problem, or an external command name, such as ACKNOWLEDGE_HOST_PROBLEM,
+// and may be a path.Match pattern like "*" or "DISABLE_*".
+type roleConfig struct {
+	permissions map[string][]string
+	// principalRoles assigns roles to principals, on top of the roles carried by their JWT
+	principalRoles map[string][]string
+}
+
+// EnableRoles restricts every route to the callers holding a role that grants its name. Roles
+// come from the groups claim of JWTs and from principalRoles. NRDP is not checked by route, its
+// check results need a role granting PROCESS_HOST_CHECK_RESULT and PROCESS_SERVICE_CHECK_RESULT
+// to the principal "nrdp".
+func (a *Api) EnableRoles(permissions map[string][]string, principalRoles map[string][]string) {
+	a.roles = &roleConfig{permissions: permissions, principalRoles: principalRoles}
+}
+
+// callerRoles returns the roles of the caller of r
+func (c *roleConfig) callerRoles(r *http.Request) []string {
+	roles := append([]string{}, auth.Roles(r)...)
+	for _, role := range c.principalRoles[auth.Principal(r)] {
+		if !stringInSlice(role, roles) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// permitted reports whether one of roles grants permission
+func (c *roleConfig) permitted(roles []string, permission string) bool {
+	for _, role := range roles {
+		for _, pattern := range c.permissions[role] {
+			if ok, _ := path.Match(pattern, permission); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// permitsCommands reports whether one of roles grants any catalogue command
+func (c *roleConfig) permitsCommands(roles []string) bool {
+	for name := range commandCatalogue {
+		if c.permitted(roles, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// missing returns the error telling the caller which permission its roles lack
+func missing(roles []string, permission string) error {
+	if len(roles) == 0 {
+		return forbidden("missing permission %s, no roles assigned", permission)
+	}
+	return forbidden("missing permission %s, roles: %s", permission, strings.Join(roles, ", "))
+}
+
+// roleHandler lets a request through when the caller holds a role granting the name of its
+// route. On command routes roles granting only command names are let through too: the commands
+// the request issues are then checked by authorizeCommands. Read routes served without
+// credentials by anonymous read are not checked, anonymous callers holding no roles.
+func (a *Api) roleHandler(commands bool) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if a.roles == nil || (!commands && auth.AnonymousRead(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var name string
+			if route := mux.CurrentRoute(r); route != nil {
+				name = route.GetName()
+			}
+
+			roles := a.roles.callerRoles(r)
+			if a.roles.permitted(roles, name) {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeGrantedKey, true)))
+				return
+			}
+			if commands && a.roles.permitsCommands(roles) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			http.Error(w, fmt.Sprintf("Forbidden: %s", missing(roles, name)), http.StatusForbidden)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// commandRoles returns a forbiddenError when the route of r was not granted and the caller holds
+// no role granting one of commands
+func (a *Api) commandRoles(r *http.Request, commands []string) error {
+	if a.roles == nil {
+		return nil
+	}
+	if granted, _ := r.Context().Value(routeGrantedKey).(bool); granted {
+		return nil
+	}
+
+	roles := a.roles.callerRoles(r)
+	for _, command := range commands {
+		name := strings.SplitN(command, ";", 2)[0]
+		if !a.roles.permitted(roles, name) {
+			return missing(roles, name)
+		}
+	}
+	return nil
+}
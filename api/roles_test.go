@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sebor/nagios-api/auth"
+	"github.com/cheekybits/is"
+)
+
+func TestRoleHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal string
+		roles     []string
+		method    string
+		path      string
+		body      string
+		code      int
+		message   string
+		contains  string
+		commands  []string
+	}{
+		{name: "read", roles: []string{"operator"}, method: http.MethodGet, path: "/hosts", code: 200},
+		{name: "read without role", method: http.MethodGet, path: "/hosts", code: 403, message: "Forbidden: missing permission hosts, no roles assigned"},
+		{name: "acknowledge route", roles: []string{"operator"}, method: http.MethodPost, path: "/acknowledge_host_problem", body: `{"hostname": "web01", "author": "jason"}`, code: 200, commands: []string{"ACKNOWLEDGE_HOST_PROBLEM;web01;2;1;1;jason;"}},
+		{name: "acknowledge command", roles: []string{"operator"}, method: http.MethodPost, path: "/commands/ACKNOWLEDGE_SVC_PROBLEM", body: `{"host_name": "web01", "service_description": "HTTP", "author": "jason", "comment": "On it"}`, code: 200, commands: []string{"ACKNOWLEDGE_SVC_PROBLEM;web01;HTTP;2;1;1;jason;On it"}},
+		{name: "disable as operator", roles: []string{"operator"}, method: http.MethodPost, path: "/disable_notifications", code: 403, message: "Error: missing permission DISABLE_NOTIFICATIONS, roles: operator"},
+		{name: "disable command as operator", roles: []string{"operator"}, method: http.MethodPost, path: "/commands/DISABLE_HOST_CHECK", body: `{"host_name": "web01"}`, code: 403},
+		{name: "audit as operator", roles: []string{"operator"}, method: http.MethodGet, path: "/audit", code: 403, message: "Forbidden: missing permission audit, roles: operator"},
+		{name: "disable as admin", roles: []string{"admin"}, method: http.MethodPost, path: "/disable_notifications", code: 200, commands: []string{"DISABLE_NOTIFICATIONS"}},
+		{name: "role assigned to principal", principal: "ci", method: http.MethodPost, path: "/disable_notifications", code: 200, commands: []string{"DISABLE_NOTIFICATIONS"}},
+		{name: "nrdp without role", method: http.MethodPost, path: "/nrdp/?token=secret&cmd=submitcheck&JSONDATA=" + `{"checkresults":[{"checkresult":{"type":"host"},"hostname":"web01","state":"0","output":"UP"}]}`, code: 200, contains: "NOT AUTHORIZED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			api := newTestApi(t)
+			api.EnableRoles(map[string][]string{
+				"operator": {"hosts", "host*", "acknowledge_*", "ACKNOWLEDGE_*"},
+				"admin":    {"*"},
+			}, map[string][]string{"ci": {"admin"}})
+
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r = auth.WithRoles(auth.WithPrincipal(r, tt.principal), tt.roles)
+			w := httptest.NewRecorder()
+			api.router.ServeHTTP(w, r)
+
+			is.Equal(w.Code, tt.code)
+			if tt.message != "" {
+				is.Equal(strings.TrimSpace(w.Body.String()), tt.message)
+			}
+			if tt.contains != "" {
+				is.True(strings.Contains(w.Body.String(), tt.contains))
+			}
+			is.Equal(strings.Join(writtenCommands(t, api), "\n"), strings.Join(tt.commands, "\n"))
+		})
+	}
+}
+
+func TestRolesWithAnonymousRead(t *testing.T) {
+	authenticator, err := auth.New(map[string]string{"reader": "r3ad"}, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.Configure(authenticator)
+	defer auth.Configure(nil)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		code   int
+	}{
+		{name: "anonymous read", method: http.MethodGet, path: "/hosts", code: 200},
+		{name: "anonymous typed read", method: http.MethodGet, path: "/v2/hoststatus", code: 200},
+		{name: "anonymous command", method: http.MethodPost, path: "/disable_notifications", code: 401},
+		{name: "anonymous audit", method: http.MethodGet, path: "/audit", code: 401},
+		// Authenticated callers still need a role granting the route
+		{name: "read without role", method: http.MethodGet, path: "/hosts", key: "r3ad", code: 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			api := newTestApi(t)
+			api.EnableRoles(map[string][]string{"admin": {"*"}}, nil)
+
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			api.router.ServeHTTP(w, r)
+
+			is.Equal(w.Code, tt.code)
+			is.Equal(len(writtenCommands(t, api)), 0)
+		})
+	}
+}
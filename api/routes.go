@@ -7,50 +7,106 @@ import (
 )
 
 func (s *Api) buildRoutes() {
-	chain := alice.New()
+	chain := alice.New(s.snapshotHandler)
+	read := chain.Append(auth.ReadHandler, s.roleHandler(false))
+	command := chain.Append(auth.AuthHandler, s.roleHandler(true))
 
-	s.router.Handle("/contacts", chain.Append(auth.AuthHandler).ThenFunc(s.HandleGetContacts)).Methods("GET")
+	// Route names are the permissions granted to roles, see roleHandler.
+	// Read-only endpoints, served without credentials when anonymous read is enabled
+	s.router.Handle("/program", read.ThenFunc(s.HandleGetProgram)).Methods("GET").Name("program")
 
-	s.router.Handle("/hosts", chain.Append(auth.AuthHandler).ThenFunc(s.HandleGetConfiguredHosts)).Methods("GET")
-	s.router.Handle("/host/{hostname:[a-z,A-Z,0-9, _.-]+}", chain.Append(auth.AuthHandler).ThenFunc(s.HandleGetHost)).Methods("GET")
-	s.router.Handle("/host/{hostname:[a-z,A-Z,0-9, _.-]+}/services", chain.Append(auth.AuthHandler).ThenFunc(s.HandleGetServicesForHost)).Methods("GET")
-	s.router.Handle("/hoststatus", chain.Append(auth.AuthHandler).ThenFunc(s.HandleGetAllHostStatus)).Methods("GET")
-	s.router.Handle("/hoststatus/{hostname:[a-z,A-Z,0-9,_.-]+}", chain.Append(auth.AuthHandler).ThenFunc(s.HandleGetHostStatusForHost)).Methods("GET")
-	s.router.Handle("/hostgroups", chain.Append(auth.AuthHandler).ThenFunc(s.HandleGetHostGroups)).Methods("GET")
+	s.router.Handle("/contacts", read.ThenFunc(s.HandleGetContacts)).Methods("GET").Name("contacts")
 
-	s.router.Handle("/services", chain.Append(auth.AuthHandler).ThenFunc(s.HandleGetConfiguredServices)).Methods("GET")
-	s.router.Handle("/servicestatus", chain.Append(auth.AuthHandler).ThenFunc(s.HandleGetServiceStatus)).Methods("GET")
-	s.router.Handle("/servicestatus/{service:[a-z,A-Z,0-9,_.-]+}", chain.Append(auth.AuthHandler).ThenFunc(s.HandleGetServiceStatusForService)).Methods("GET")
+	s.router.Handle("/hosts", read.ThenFunc(s.HandleGetConfiguredHosts)).Methods("GET").Name("hosts")
+	s.router.Handle("/host/{hostname:[a-z,A-Z,0-9, _.-]+}", read.ThenFunc(s.HandleGetHost)).Methods("GET").Name("host")
+	s.router.Handle("/host/{hostname:[a-z,A-Z,0-9, _.-]+}/services", read.ThenFunc(s.HandleGetServicesForHost)).Methods("GET").Name("host_services")
+	s.router.Handle("/host/{hostname:[a-z,A-Z,0-9, _.-]+}/comments", read.ThenFunc(s.HandleGetCommentsForHost)).Methods("GET").Name("host_comments")
+	s.router.Handle("/hoststatus", read.ThenFunc(s.HandleGetAllHostStatus)).Methods("GET").Name("hoststatus")
+	s.router.Handle("/hoststatus/{hostname:[a-z,A-Z,0-9,_.-]+}", read.ThenFunc(s.HandleGetHostStatusForHost)).Methods("GET").Name("hoststatus_host")
+	s.router.Handle("/hostgroups", read.ThenFunc(s.HandleGetHostGroups)).Methods("GET").Name("hostgroups")
+
+	s.router.Handle("/services", read.ThenFunc(s.HandleGetConfiguredServices)).Methods("GET").Name("services")
+	s.router.Handle("/servicestatus", read.ThenFunc(s.HandleGetServiceStatus)).Methods("GET").Name("servicestatus")
+	s.router.Handle("/servicestatus/{service:[a-z,A-Z,0-9,_.-]+}", read.ThenFunc(s.HandleGetServiceStatusForService)).Methods("GET").Name("servicestatus_service")
+	s.router.Handle("/servicestatus/{service:[a-z,A-Z,0-9,_.-]+}/comments", read.ThenFunc(s.HandleGetCommentsForService)).Methods("GET").Name("service_comments")
+
+	s.router.Handle("/comments", read.ThenFunc(s.HandleGetComments)).Methods("GET").Name("comments")
+	s.router.Handle("/downtimes", read.ThenFunc(s.HandleGetDowntimes)).Methods("GET").Name("downtimes")
+
+	// Typed status, see status_v2.go. The unversioned routes keep serving every field as a string.
+	s.router.Handle("/v2/host/{hostname:[a-z,A-Z,0-9, _.-]+}/services", read.ThenFunc(s.HandleGetServicesForHostV2)).Methods("GET").Name("v2_host_services")
+	s.router.Handle("/v2/hoststatus", read.ThenFunc(s.HandleGetAllHostStatusV2)).Methods("GET").Name("v2_hoststatus")
+	s.router.Handle("/v2/hoststatus/{hostname:[a-z,A-Z,0-9,_.-]+}", read.ThenFunc(s.HandleGetHostStatusForHostV2)).Methods("GET").Name("v2_hoststatus_host")
+	s.router.Handle("/v2/servicestatus", read.ThenFunc(s.HandleGetServiceStatusV2)).Methods("GET").Name("v2_servicestatus")
+	s.router.Handle("/v2/servicestatus/{service:[a-z,A-Z,0-9,_.-]+}", read.ThenFunc(s.HandleGetServiceStatusForServiceV2)).Methods("GET").Name("v2_servicestatus_service")
 
 	// Nagios External Command Handlers
-	s.router.Handle("/host/{hostname:[a-z,A-Z,0-9, _.-]+}/force", chain.Append(auth.AuthHandler).ThenFunc(s.HandleForcedHostServiceChecks)).Methods("GET")
-	s.router.Handle("/disable_notifications", chain.Append(auth.AuthHandler).ThenFunc(s.HandleDisableNotifications)).Methods("POST")
-	s.router.Handle("/enable_notifications", chain.Append(auth.AuthHandler).ThenFunc(s.HandleEnableNotifications)).Methods("POST")
-	s.router.Handle("/disable_host_check", chain.Append(auth.AuthHandler).ThenFunc(s.HandleDisableHostCheck)).Methods("POST")
-	s.router.Handle("/enable_host_check", chain.Append(auth.AuthHandler).ThenFunc(s.HandleEnableHostCheck)).Methods("POST")
-	s.router.Handle("/disable_host_notifications", chain.Append(auth.AuthHandler).ThenFunc(s.HandleDisableHostNotifications)).Methods("POST")
-	s.router.Handle("/enable_host_notifications", chain.Append(auth.AuthHandler).ThenFunc(s.HandleEnableHostNotifications)).Methods("POST")
-	s.router.Handle("/acknowledge_host_problem", chain.Append(auth.AuthHandler).ThenFunc(s.HandleAcknowledgeHostProblem)).Methods("POST")
-	s.router.Handle("/acknowledge_service_problem", chain.Append(auth.AuthHandler).ThenFunc(s.HandleAcknowledgeServiceProblem)).Methods("POST")
-	s.router.Handle("/add_host_comment", chain.Append(auth.AuthHandler).ThenFunc(s.HandleAddHostComment)).Methods("POST")
-	s.router.Handle("/add_svc_comment", chain.Append(auth.AuthHandler).ThenFunc(s.HandleAddServiceComment)).Methods("POST")
-	s.router.Handle("/del_all_host_comment", chain.Append(auth.AuthHandler).ThenFunc(s.HandleDeleteAllHostCommnet)).Methods("POST")
-	s.router.Handle("/del_all_svc_comment", chain.Append(auth.AuthHandler).ThenFunc(s.HandleDeleteAllServiceComment)).Methods("POST")
-	s.router.Handle("/del_host_comment", chain.Append(auth.AuthHandler).ThenFunc(s.HandleDeleteHostComment)).Methods("POST")
-	s.router.Handle("/del_svc_comment", chain.Append(auth.AuthHandler).ThenFunc(s.HandleDeleteServiceComment)).Methods("POST")
-	s.router.Handle("/disable_all_notification_beyond_host", chain.Append(auth.AuthHandler).ThenFunc(s.HandleDisableAllNotificationBeyondHost)).Methods("POST")
-	s.router.Handle("/enable_all_notification_beyond_host", chain.Append(auth.AuthHandler).ThenFunc(s.HandleEnableAllNotificationBeyondHost)).Methods("POST")
-	s.router.Handle("/disable_hostgroup_host_checks", chain.Append(auth.AuthHandler).ThenFunc(s.HandleDisableHostgroupHostChecks)).Methods("POST")
-	s.router.Handle("/enable_hostgroup_host_checks", chain.Append(auth.AuthHandler).ThenFunc(s.HandleEnableHostgroupHostChecks)).Methods("POST")
-	s.router.Handle("/disable_hostgroup_host_notifications", chain.Append(auth.AuthHandler).ThenFunc(s.HandleDisableHostgroupHostNotification)).Methods("POST")
-	s.router.Handle("/enable_hostgroup_host_notifications", chain.Append(auth.AuthHandler).ThenFunc(s.HandleEnableHostgroupHostNotification)).Methods("POST")
-	s.router.Handle("/disable_hostgroup_svc_checks", chain.Append(auth.AuthHandler).ThenFunc(s.HandleDisableHostgroupServiceChecks)).Methods("POST")
-	s.router.Handle("/enable_hostgroup_svc_checks", chain.Append(auth.AuthHandler).ThenFunc(s.HandleEnableHostgroupServiceChecks)).Methods("POST")
-	s.router.Handle("/disable_hostgroup_svc_notifications", chain.Append(auth.AuthHandler).ThenFunc(s.HandleDisableHostgroupServiceNotifications)).Methods("POST")
-	s.router.Handle("/enable_hostgroup_svc_notifications", chain.Append(auth.AuthHandler).ThenFunc(s.HandleEnableHostgroupServiceNotifications)).Methods("POST")
-	s.router.Handle("/disable_host_and_child_notifications", chain.Append(auth.AuthHandler).ThenFunc(s.HandleDisableHostandChildNotifications)).Methods("POST")
-	s.router.Handle("/enable_host_and_child_notifications", chain.Append(auth.AuthHandler).ThenFunc(s.HandleEnableHostandChildNotifications)).Methods("POST")
-	s.router.Handle("/schedule_host_downtime", chain.Append(auth.AuthHandler).ThenFunc(s.HandleScheduleHostDowntime)).Methods("POST")
-	s.router.Handle("/force_service_checks", chain.Append(auth.AuthHandler).ThenFunc(s.HandleScheduleForcedHostServiceChecks)).Methods("POST")
-	s.router.Handle("/force_host_checks", chain.Append(auth.AuthHandler).ThenFunc(s.HandleScheduleForcedHostCheck)).Methods("POST")
+	s.router.Handle("/host/{hostname:[a-z,A-Z,0-9, _.-]+}/force", command.ThenFunc(s.HandleForcedHostServiceChecks)).Methods("GET").Name("host_force")
+	s.router.Handle("/disable_notifications", command.ThenFunc(s.HandleDisableNotifications)).Methods("POST").Name("disable_notifications")
+	s.router.Handle("/enable_notifications", command.ThenFunc(s.HandleEnableNotifications)).Methods("POST").Name("enable_notifications")
+	s.router.Handle("/start_executing_host_checks", command.ThenFunc(s.HandleStartExecutingHostChecks)).Methods("POST").Name("start_executing_host_checks")
+	s.router.Handle("/stop_executing_host_checks", command.ThenFunc(s.HandleStopExecutingHostChecks)).Methods("POST").Name("stop_executing_host_checks")
+	s.router.Handle("/start_executing_svc_checks", command.ThenFunc(s.HandleStartExecutingServiceChecks)).Methods("POST").Name("start_executing_svc_checks")
+	s.router.Handle("/stop_executing_svc_checks", command.ThenFunc(s.HandleStopExecutingServiceChecks)).Methods("POST").Name("stop_executing_svc_checks")
+	s.router.Handle("/enable_event_handlers", command.ThenFunc(s.HandleEnableEventHandlers)).Methods("POST").Name("enable_event_handlers")
+	s.router.Handle("/disable_event_handlers", command.ThenFunc(s.HandleDisableEventHandlers)).Methods("POST").Name("disable_event_handlers")
+	s.router.Handle("/enable_flap_detection", command.ThenFunc(s.HandleEnableFlapDetection)).Methods("POST").Name("enable_flap_detection")
+	s.router.Handle("/disable_flap_detection", command.ThenFunc(s.HandleDisableFlapDetection)).Methods("POST").Name("disable_flap_detection")
+	s.router.Handle("/start_accepting_passive_host_checks", command.ThenFunc(s.HandleStartAcceptingPassiveHostChecks)).Methods("POST").Name("start_accepting_passive_host_checks")
+	s.router.Handle("/stop_accepting_passive_host_checks", command.ThenFunc(s.HandleStopAcceptingPassiveHostChecks)).Methods("POST").Name("stop_accepting_passive_host_checks")
+	s.router.Handle("/start_accepting_passive_svc_checks", command.ThenFunc(s.HandleStartAcceptingPassiveServiceChecks)).Methods("POST").Name("start_accepting_passive_svc_checks")
+	s.router.Handle("/stop_accepting_passive_svc_checks", command.ThenFunc(s.HandleStopAcceptingPassiveServiceChecks)).Methods("POST").Name("stop_accepting_passive_svc_checks")
+	s.router.Handle("/restart_program", command.ThenFunc(s.HandleRestartProgram)).Methods("POST").Name("restart_program")
+	s.router.Handle("/shutdown_program", command.ThenFunc(s.HandleShutdownProgram)).Methods("POST").Name("shutdown_program")
+	s.router.Handle("/save_state_information", command.ThenFunc(s.HandleSaveStateInformation)).Methods("POST").Name("save_state_information")
+	s.router.Handle("/disable_host_check", command.ThenFunc(s.HandleDisableHostCheck)).Methods("POST").Name("disable_host_check")
+	s.router.Handle("/enable_host_check", command.ThenFunc(s.HandleEnableHostCheck)).Methods("POST").Name("enable_host_check")
+	s.router.Handle("/disable_host_notifications", command.ThenFunc(s.HandleDisableHostNotifications)).Methods("POST").Name("disable_host_notifications")
+	s.router.Handle("/enable_host_notifications", command.ThenFunc(s.HandleEnableHostNotifications)).Methods("POST").Name("enable_host_notifications")
+	s.router.Handle("/disable_svc_check", command.ThenFunc(s.HandleDisableServiceCheck)).Methods("POST").Name("disable_svc_check")
+	s.router.Handle("/enable_svc_check", command.ThenFunc(s.HandleEnableServiceCheck)).Methods("POST").Name("enable_svc_check")
+	s.router.Handle("/disable_svc_notifications", command.ThenFunc(s.HandleDisableServiceNotifications)).Methods("POST").Name("disable_svc_notifications")
+	s.router.Handle("/enable_svc_notifications", command.ThenFunc(s.HandleEnableServiceNotifications)).Methods("POST").Name("enable_svc_notifications")
+	s.router.Handle("/remove_svc_acknowledgement", command.ThenFunc(s.HandleRemoveServiceAcknowledgement)).Methods("POST").Name("remove_svc_acknowledgement")
+	s.router.Handle("/acknowledge_host_problem", command.ThenFunc(s.HandleAcknowledgeHostProblem)).Methods("POST").Name("acknowledge_host_problem")
+	s.router.Handle("/acknowledge_service_problem", command.ThenFunc(s.HandleAcknowledgeServiceProblem)).Methods("POST").Name("acknowledge_service_problem")
+	s.router.Handle("/add_host_comment", command.ThenFunc(s.HandleAddHostComment)).Methods("POST").Name("add_host_comment")
+	s.router.Handle("/add_svc_comment", command.ThenFunc(s.HandleAddServiceComment)).Methods("POST").Name("add_svc_comment")
+	s.router.Handle("/del_all_host_comment", command.ThenFunc(s.HandleDeleteAllHostCommnet)).Methods("POST").Name("del_all_host_comment")
+	s.router.Handle("/del_all_svc_comment", command.ThenFunc(s.HandleDeleteAllServiceComment)).Methods("POST").Name("del_all_svc_comment")
+	s.router.Handle("/del_host_comment", command.ThenFunc(s.HandleDeleteHostComment)).Methods("POST").Name("del_host_comment")
+	s.router.Handle("/del_svc_comment", command.ThenFunc(s.HandleDeleteServiceComment)).Methods("POST").Name("del_svc_comment")
+	s.router.Handle("/disable_all_notification_beyond_host", command.ThenFunc(s.HandleDisableAllNotificationBeyondHost)).Methods("POST").Name("disable_all_notification_beyond_host")
+	s.router.Handle("/enable_all_notification_beyond_host", command.ThenFunc(s.HandleEnableAllNotificationBeyondHost)).Methods("POST").Name("enable_all_notification_beyond_host")
+	s.router.Handle("/disable_hostgroup_host_checks", command.ThenFunc(s.HandleDisableHostgroupHostChecks)).Methods("POST").Name("disable_hostgroup_host_checks")
+	s.router.Handle("/enable_hostgroup_host_checks", command.ThenFunc(s.HandleEnableHostgroupHostChecks)).Methods("POST").Name("enable_hostgroup_host_checks")
+	s.router.Handle("/disable_hostgroup_host_notifications", command.ThenFunc(s.HandleDisableHostgroupHostNotification)).Methods("POST").Name("disable_hostgroup_host_notifications")
+	s.router.Handle("/enable_hostgroup_host_notifications", command.ThenFunc(s.HandleEnableHostgroupHostNotification)).Methods("POST").Name("enable_hostgroup_host_notifications")
+	s.router.Handle("/disable_hostgroup_svc_checks", command.ThenFunc(s.HandleDisableHostgroupServiceChecks)).Methods("POST").Name("disable_hostgroup_svc_checks")
+	s.router.Handle("/enable_hostgroup_svc_checks", command.ThenFunc(s.HandleEnableHostgroupServiceChecks)).Methods("POST").Name("enable_hostgroup_svc_checks")
+	s.router.Handle("/disable_hostgroup_svc_notifications", command.ThenFunc(s.HandleDisableHostgroupServiceNotifications)).Methods("POST").Name("disable_hostgroup_svc_notifications")
+	s.router.Handle("/enable_hostgroup_svc_notifications", command.ThenFunc(s.HandleEnableHostgroupServiceNotifications)).Methods("POST").Name("enable_hostgroup_svc_notifications")
+	s.router.Handle("/disable_host_and_child_notifications", command.ThenFunc(s.HandleDisableHostandChildNotifications)).Methods("POST").Name("disable_host_and_child_notifications")
+	s.router.Handle("/enable_host_and_child_notifications", command.ThenFunc(s.HandleEnableHostandChildNotifications)).Methods("POST").Name("enable_host_and_child_notifications")
+	s.router.Handle("/schedule_host_downtime", command.ThenFunc(s.HandleScheduleHostDowntime)).Methods("POST").Name("schedule_host_downtime")
+	s.router.Handle("/schedule_svc_downtime", command.ThenFunc(s.HandleScheduleServiceDowntime)).Methods("POST").Name("schedule_svc_downtime")
+	s.router.Handle("/schedule_host_check", command.ThenFunc(s.HandleScheduleHostCheck)).Methods("POST").Name("schedule_host_check")
+	s.router.Handle("/schedule_svc_check", command.ThenFunc(s.HandleScheduleServiceCheck)).Methods("POST").Name("schedule_svc_check")
+	s.router.Handle("/force_svc_check", command.ThenFunc(s.HandleScheduleForcedServiceCheck)).Methods("POST").Name("force_svc_check")
+	s.router.Handle("/process_host_check_result", command.ThenFunc(s.HandleProcessHostCheckResult)).Methods("POST").Name("process_host_check_result")
+	s.router.Handle("/process_service_check_result", command.ThenFunc(s.HandleProcessServiceCheckResult)).Methods("POST").Name("process_service_check_result")
+	// NRDP clients authenticate with their token
+	s.router.Handle("/nrdp/", chain.ThenFunc(s.HandleNrdp)).Methods("GET", "POST").Name("nrdp")
+	s.router.Handle("/nrdp", chain.ThenFunc(s.HandleNrdp)).Methods("GET", "POST").Name("nrdp")
+	s.router.Handle("/del_host_downtime", command.ThenFunc(s.HandleDeleteHostDowntime)).Methods("POST").Name("del_host_downtime")
+	s.router.Handle("/del_svc_downtime", command.ThenFunc(s.HandleDeleteServiceDowntime)).Methods("POST").Name("del_svc_downtime")
+	s.router.Handle("/del_downtime_by_host_name", command.ThenFunc(s.HandleDeleteDowntimeByHostName)).Methods("POST").Name("del_downtime_by_host_name")
+	s.router.Handle("/force_service_checks", command.ThenFunc(s.HandleScheduleForcedHostServiceChecks)).Methods("POST").Name("force_service_checks")
+	s.router.Handle("/force_host_checks", command.ThenFunc(s.HandleScheduleForcedHostCheck)).Methods("POST").Name("force_host_checks")
+	s.router.Handle("/commands", read.ThenFunc(s.HandleGetCommands)).Methods("GET").Name("commands")
+	s.router.Handle("/commands", command.ThenFunc(s.HandleBatchCommands)).Methods("POST").Name("batch_commands")
+	s.router.Handle("/commands/{command:[A-Z_]+}", command.ThenFunc(s.HandleExecuteCommand)).Methods("POST").Name("execute_command")
+	s.router.Handle("/commands/{id:[0-9a-f]+}", read.ThenFunc(s.HandleGetCommandConfirmation)).Methods("GET").Name("command_confirmation")
+	s.router.Handle("/bulk_command", command.ThenFunc(s.HandleBulkCommand)).Methods("POST").Name("bulk_command")
+	s.router.Handle("/audit", chain.Append(auth.AuthHandler, s.roleHandler(false)).ThenFunc(s.HandleGetAudit)).Methods("GET").Name("audit")
 }
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// snapshot is the status and static data served together, with the modification times of the
+// files they were read from. A snapshot is never modified once published, so requests read it
+// without locking and see status and static data of the same generation throughout.
+type snapshot struct {
+	// generation counts the snapshots published, it grows by one with every file read
+	generation uint64
+	status     *StatusData
+	static     *StaticData
+	statusTime time.Time
+	staticTime time.Time
+}
+
+// emptySnapshot is served until the files were read
+var emptySnapshot = &snapshot{status: NewStatusData(), static: NewStaticData()}
+
+// snapshot returns the snapshot published last
+func (a *Api) snapshot() *snapshot {
+	if s, ok := a.current.Load().(*snapshot); ok {
+		return s
+	}
+	return emptySnapshot
+}
+
+// snapshotOf returns the snapshot r is served from, see snapshotHandler
+func (a *Api) snapshotOf(r *http.Request) *snapshot {
+	if s, ok := r.Context().Value(snapshotKey).(*snapshot); ok {
+		return s
+	}
+	return a.snapshot()
+}
+
+// publish replaces the current snapshot by a copy changed by update
+func (a *Api) publish(update func(next *snapshot)) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	next := *a.snapshot()
+	next.generation++
+	update(&next)
+	a.current.Store(&next)
+}
+
+// publishStatus publishes status data read from a status.dat modified at modTime
+func (a *Api) publishStatus(data *StatusData, modTime time.Time) {
+	a.publish(func(next *snapshot) {
+		next.status, next.statusTime = data, modTime
+	})
+}
+
+// publishStatic publishes static data read from an objects.cache modified at modTime
+func (a *Api) publishStatic(data *StaticData, modTime time.Time) {
+	a.publish(func(next *snapshot) {
+		next.static, next.staticTime = data, modTime
+	})
+}
+
+// snapshotHandler serves every request from the snapshot current when it arrived. Its generation
+// and the modification times of status.dat and objects.cache are sent as response headers, with
+// the later of both as Last-Modified.
+func (a *Api) snapshotHandler(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		snap := a.snapshot()
+
+		header := w.Header()
+		header.Set("X-Nagios-Generation", strconv.FormatUint(snap.generation, 10))
+		if !snap.statusTime.IsZero() {
+			header.Set("X-Nagios-Status-Modified", snap.statusTime.UTC().Format(time.RFC3339))
+		}
+		if !snap.staticTime.IsZero() {
+			header.Set("X-Nagios-Objects-Modified", snap.staticTime.UTC().Format(time.RFC3339))
+		}
+		modified := snap.statusTime
+		if snap.staticTime.After(modified) {
+			modified = snap.staticTime
+		}
+		if !modified.IsZero() {
+			header.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), snapshotKey, snap)))
+	}
+	return http.HandlerFunc(fn)
+}
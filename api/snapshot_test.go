@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+)
+
+func TestSnapshotHeaders(t *testing.T) {
+	is := is.New(t)
+	api := newTestApi(t)
+
+	// Until status.dat was read only the objects.cache time is known
+	w := httptest.NewRecorder()
+	api.router.ServeHTTP(w, httptest.NewRequest("GET", "/hosts", nil))
+	is.Equal(w.Code, 200)
+	is.Equal(w.Header().Get("X-Nagios-Generation"), "1")
+	is.Equal(w.Header().Get("X-Nagios-Status-Modified"), "")
+
+	statusTime := time.Date(2017, 1, 10, 21, 15, 0, 0, time.UTC)
+	api.publishStatus(NewStatusData(), statusTime)
+	staticTime := time.Date(2017, 1, 10, 20, 0, 0, 0, time.UTC)
+	api.publishStatic(api.snapshot().static, staticTime)
+
+	w = httptest.NewRecorder()
+	api.router.ServeHTTP(w, httptest.NewRequest("GET", "/hoststatus", nil))
+	is.Equal(w.Header().Get("X-Nagios-Generation"), "3")
+	is.Equal(w.Header().Get("X-Nagios-Status-Modified"), "2017-01-10T21:15:00Z")
+	is.Equal(w.Header().Get("X-Nagios-Objects-Modified"), "2017-01-10T20:00:00Z")
+	is.Equal(w.Header().Get("Last-Modified"), "Tue, 10 Jan 2017 21:15:00 GMT")
+}
+
+func TestSnapshotPerRequest(t *testing.T) {
+	is := is.New(t)
+	api := newTestApi(t)
+
+	var served *snapshot
+	handler := api.snapshotHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Data published while the request is served is left for the next one
+		api.publishStatus(NewStatusData(), time.Now())
+		served = api.snapshotOf(r)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/hoststatus", nil))
+	is.Equal(w.Header().Get("X-Nagios-Generation"), strconv.FormatUint(served.generation, 10))
+	is.Equal(served.generation+1, api.snapshot().generation)
+	is.True(served.statusTime.IsZero())
+}
+
+func TestSnapshotTargets(t *testing.T) {
+	is := is.New(t)
+	api := newTestApi(t)
+
+	handler := api.snapshotHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Targets are checked against the objects.cache the request is served from
+		api.publishStatic(NewStaticData(), time.Now())
+		is.True(api.requireHost(w, r, "web01"))
+		is.NoErr(api.unknownTarget(r, commandCatalogue["DISABLE_SVC_CHECK"], []interface{}{"web01", "HTTP"}))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/commands/DISABLE_SVC_CHECK", nil))
+	is.Equal(w.Code, 200)
+	is.False(api.snapshot().static.hasHost("web01"))
+}
+
+func TestSnapshotConcurrentPublish(t *testing.T) {
+	is := is.New(t)
+	api := newTestApi(t)
+	start := api.snapshot().generation
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				api.publishStatus(NewStatusData(), time.Now())
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				w := httptest.NewRecorder()
+				api.router.ServeHTTP(w, httptest.NewRequest("GET", "/servicestatus", nil))
+				if w.Code != 200 {
+					t.Error(w.Code)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every publication is counted and none is lost
+	is.Equal(api.snapshot().generation, start+500)
+	is.NotNil(api.snapshot().static)
+}
@@ -0,0 +1,471 @@
+package api
+
+// The fieldRef methods return the string field of an object holding the status.dat key, or nil
+// when the object has no such field. They let the status parser set fields without reflection and
+// are checked against the JSON tags by TestFieldRef.
+
+func (o *ContactStatus) fieldRef(key string) *string {
+	switch key {
+	case "contact_name":
+		return &o.ContactName
+	case "host_notification_period":
+		return &o.HostNotificationPeriod
+	case "host_notifications_enabled":
+		return &o.HostNotificationsEnabled
+	case "last_host_notification":
+		return &o.LastHostNotification
+	case "last_service_notification":
+		return &o.LastServiceNotification
+	case "modified_attributes":
+		return &o.ModifiedAttributes
+	case "modified_host_attributes":
+		return &o.ModifiedHostAttributes
+	case "modified_service_attributes":
+		return &o.ModifiedServiceAttributes
+	case "service_notification_period":
+		return &o.ServiceNotificationPeriod
+	case "service_notifications_enabled":
+		return &o.ServiceNotificationsEnabled
+	}
+	return nil
+}
+
+func (o *HostStatus) fieldRef(key string) *string {
+	switch key {
+	case "acknowledgement_type":
+		return &o.AcknowledgementType
+	case "active_checks_enabled":
+		return &o.ActiveChecksEnabled
+	case "check_command":
+		return &o.CheckCommand
+	case "check_execution_time":
+		return &o.CheckExecutionTime
+	case "check_interval":
+		return &o.CheckInterval
+	case "check_latency":
+		return &o.CheckLatency
+	case "check_options":
+		return &o.CheckOptions
+	case "check_period":
+		return &o.CheckPeriod
+	case "check_type":
+		return &o.CheckType
+	case "current_attempt":
+		return &o.CurrentAttempt
+	case "current_event_id":
+		return &o.CurrentEventID
+	case "current_notification_id":
+		return &o.CurrentNotificationID
+	case "current_notification_number":
+		return &o.CurrentNotificationNumber
+	case "current_problem_id":
+		return &o.CurrentProblemID
+	case "current_state":
+		return &o.CurrentState
+	case "event_handler":
+		return &o.EventHandler
+	case "event_handler_enabled":
+		return &o.EventHandlerEnabled
+	case "flap_detection_enabled":
+		return &o.FlapDetectionEnabled
+	case "has_been_checked":
+		return &o.HasBeenChecked
+	case "host_name":
+		return &o.HostName
+	case "is_flapping":
+		return &o.IsFlapping
+	case "last_check":
+		return &o.LastCheck
+	case "last_event_id":
+		return &o.LastEventID
+	case "last_hard_state":
+		return &o.LastHardState
+	case "last_hard_state_change":
+		return &o.LastHardStateChange
+	case "last_notification":
+		return &o.LastNotification
+	case "last_problem_id":
+		return &o.LastProblemID
+	case "last_state_change":
+		return &o.LastStateChange
+	case "last_time_down":
+		return &o.LastTimeDown
+	case "last_time_unreachable":
+		return &o.LastTimeUnreachable
+	case "last_time_up":
+		return &o.LastTimeUp
+	case "last_update":
+		return &o.LastUpdate
+	case "long_plugin_output":
+		return &o.LongPluginOutput
+	case "max_attempts":
+		return &o.MaxAttempts
+	case "modified_attributes":
+		return &o.ModifiedAttributes
+	case "next_check":
+		return &o.NextCheck
+	case "next_notification":
+		return &o.NextNotification
+	case "no_more_notifications":
+		return &o.NoMoreNotifications
+	case "notification_period":
+		return &o.NotificationPeriod
+	case "notifications_enabled":
+		return &o.NotificationsEnabled
+	case "obsess":
+		return &o.Obsess
+	case "passive_checks_enabled":
+		return &o.PassiveChecksEnabled
+	case "percent_state_change":
+		return &o.PercentStateChange
+	case "performance_data":
+		return &o.PerformanceData
+	case "plugin_output":
+		return &o.PluginOutput
+	case "problem_has_been_acknowledged":
+		return &o.ProblemHasBeenAcknowledged
+	case "process_performance_data":
+		return &o.ProcessPerformanceData
+	case "retry_interval":
+		return &o.RetryInterval
+	case "scheduled_downtime_depth":
+		return &o.ScheduledDowntimeDepth
+	case "should_be_scheduled":
+		return &o.ShouldBeScheduled
+	case "state_type":
+		return &o.StateType
+	}
+	return nil
+}
+
+func (o *InfoStatus) fieldRef(key string) *string {
+	switch key {
+	case "created":
+		return &o.Created
+	case "version":
+		return &o.Version
+	case "last_update_check":
+		return &o.LastUpdateCheck
+	case "update_available":
+		return &o.UpdateAvailable
+	case "last_version":
+		return &o.LastVersion
+	case "new_version":
+		return &o.NewVersion
+	}
+	return nil
+}
+
+func (o *ProgramStatus) fieldRef(key string) *string {
+	switch key {
+	case "active_host_checks_enabled":
+		return &o.ActiveHostChecksEnabled
+	case "active_ondemand_host_check_stats":
+		return &o.ActiveOndemandHostCheckStats
+	case "active_ondemand_service_check_stats":
+		return &o.ActiveOndemandServiceCheckStats
+	case "active_scheduled_host_check_stats":
+		return &o.ActiveScheduledHostCheckStats
+	case "active_scheduled_service_check_stats":
+		return &o.ActiveScheduledServiceCheckStats
+	case "active_service_checks_enabled":
+		return &o.ActiveServiceChecksEnabled
+	case "cached_host_check_stats":
+		return &o.CachedHostCheckStats
+	case "cached_service_check_stats":
+		return &o.CachedServiceCheckStats
+	case "check_host_freshness":
+		return &o.CheckHostFreshness
+	case "check_service_freshness":
+		return &o.CheckServiceFreshness
+	case "daemon_mode":
+		return &o.DaemonMode
+	case "enable_event_handlers":
+		return &o.EnableEventHandlers
+	case "enable_flap_detection":
+		return &o.EnableFlapDetection
+	case "enable_notifications":
+		return &o.EnableNotifications
+	case "external_command_stats":
+		return &o.ExternalCommandStats
+	case "global_host_event_handler":
+		return &o.GlobalHostEventHandler
+	case "global_service_event_handler":
+		return &o.GlobalServiceEventHandler
+	case "last_log_rotation":
+		return &o.LastLogRotation
+	case "modified_host_attributes":
+		return &o.ModifiedHostAttributes
+	case "modified_service_attributes":
+		return &o.ModifiedServiceAttributes
+	case "nagios_pid":
+		return &o.NagiosPid
+	case "next_comment_id":
+		return &o.NextCommentID
+	case "next_downtime_id":
+		return &o.NextDowntimeID
+	case "next_event_id":
+		return &o.NextEventID
+	case "next_notification_id":
+		return &o.NextNotificationID
+	case "next_problem_id":
+		return &o.NextProblemID
+	case "obsess_over_hosts":
+		return &o.ObsessOverHosts
+	case "obsess_over_services":
+		return &o.ObsessOverServices
+	case "parallel_host_check_stats":
+		return &o.ParallelHostCheckStats
+	case "passive_host_check_stats":
+		return &o.PassiveHostCheckStats
+	case "passive_host_checks_enabled":
+		return &o.PassiveHostChecksEnabled
+	case "passive_service_check_stats":
+		return &o.PassiveServiceCheckStats
+	case "passive_service_checks_enabled":
+		return &o.PassiveServiceChecksEnabled
+	case "process_performance_data":
+		return &o.ProcessPerformanceData
+	case "program_start":
+		return &o.ProgramStart
+	case "serial_host_check_stats":
+		return &o.SerialHostCheckStats
+	}
+	return nil
+}
+
+func (o *ServiceStatus) fieldRef(key string) *string {
+	switch key {
+	case "acknowledgement_type":
+		return &o.AcknowledgementType
+	case "active_checks_enabled":
+		return &o.ActiveChecksEnabled
+	case "check_command":
+		return &o.CheckCommand
+	case "check_execution_time":
+		return &o.CheckExecutionTime
+	case "check_interval":
+		return &o.CheckInterval
+	case "check_latency":
+		return &o.CheckLatency
+	case "check_options":
+		return &o.CheckOptions
+	case "check_period":
+		return &o.CheckPeriod
+	case "check_type":
+		return &o.CheckType
+	case "current_attempt":
+		return &o.CurrentAttempt
+	case "current_event_id":
+		return &o.CurrentEventID
+	case "current_notification_id":
+		return &o.CurrentNotificationID
+	case "current_notification_number":
+		return &o.CurrentNotificationNumber
+	case "current_problem_id":
+		return &o.CurrentProblemID
+	case "current_state":
+		return &o.CurrentState
+	case "event_handler":
+		return &o.EventHandler
+	case "event_handler_enabled":
+		return &o.EventHandlerEnabled
+	case "flap_detection_enabled":
+		return &o.FlapDetectionEnabled
+	case "has_been_checked":
+		return &o.HasBeenChecked
+	case "host_name":
+		return &o.HostName
+	case "is_flapping":
+		return &o.IsFlapping
+	case "last_check":
+		return &o.LastCheck
+	case "last_event_id":
+		return &o.LastEventID
+	case "last_hard_state":
+		return &o.LastHardState
+	case "last_hard_state_change":
+		return &o.LastHardStateChange
+	case "last_notification":
+		return &o.LastNotification
+	case "last_problem_id":
+		return &o.LastProblemID
+	case "last_state_change":
+		return &o.LastStateChange
+	case "last_time_critical":
+		return &o.LastTimeCritical
+	case "last_time_ok":
+		return &o.LastTimeOk
+	case "last_time_unknown":
+		return &o.LastTimeUnknown
+	case "last_time_warning":
+		return &o.LastTimeWarning
+	case "last_update":
+		return &o.LastUpdate
+	case "long_plugin_output":
+		return &o.LongPluginOutput
+	case "max_attempts":
+		return &o.MaxAttempts
+	case "modified_attributes":
+		return &o.ModifiedAttributes
+	case "next_check":
+		return &o.NextCheck
+	case "next_notification":
+		return &o.NextNotification
+	case "no_more_notifications":
+		return &o.NoMoreNotifications
+	case "notification_period":
+		return &o.NotificationPeriod
+	case "notifications_enabled":
+		return &o.NotificationsEnabled
+	case "obsess":
+		return &o.Obsess
+	case "passive_checks_enabled":
+		return &o.PassiveChecksEnabled
+	case "percent_state_change":
+		return &o.PercentStateChange
+	case "performance_data":
+		return &o.PerformanceData
+	case "plugin_output":
+		return &o.PluginOutput
+	case "problem_has_been_acknowledged":
+		return &o.ProblemHasBeenAcknowledged
+	case "process_performance_data":
+		return &o.ProcessPerformanceData
+	case "retry_interval":
+		return &o.RetryInterval
+	case "scheduled_downtime_depth":
+		return &o.ScheduledDowntimeDepth
+	case "service_description":
+		return &o.ServiceDescription
+	case "should_be_scheduled":
+		return &o.ShouldBeScheduled
+	case "state_type":
+		return &o.StateType
+	}
+	return nil
+}
+
+func (o *HostComment) fieldRef(key string) *string {
+	switch key {
+	case "host_name":
+		return &o.HostName
+	case "entry_type":
+		return &o.EntryType
+	case "comment_id":
+		return &o.CommentID
+	case "source":
+		return &o.Source
+	case "persistent":
+		return &o.Persistent
+	case "entry_time":
+		return &o.EntryTime
+	case "expires":
+		return &o.Expires
+	case "expire_time":
+		return &o.ExpireTime
+	case "author":
+		return &o.Author
+	case "comment_data":
+		return &o.CommentData
+	}
+	return nil
+}
+
+func (o *ServiceComment) fieldRef(key string) *string {
+	switch key {
+	case "host_name":
+		return &o.HostName
+	case "service_description":
+		return &o.ServiceDescription
+	case "entry_type":
+		return &o.EntryType
+	case "comment_id":
+		return &o.CommentID
+	case "source":
+		return &o.Source
+	case "persistent":
+		return &o.Persistent
+	case "entry_time":
+		return &o.EntryTime
+	case "expires":
+		return &o.Expires
+	case "expire_time":
+		return &o.ExpireTime
+	case "author":
+		return &o.Author
+	case "comment_data":
+		return &o.CommentData
+	}
+	return nil
+}
+
+func (o *HostDowntime) fieldRef(key string) *string {
+	switch key {
+	case "host_name":
+		return &o.HostName
+	case "downtime_id":
+		return &o.DowntimeID
+	case "comment_id":
+		return &o.CommentID
+	case "entry_time":
+		return &o.EntryTime
+	case "start_time":
+		return &o.StartTime
+	case "flex_downtime_start":
+		return &o.FlexDowntimeStart
+	case "end_time":
+		return &o.EndTime
+	case "triggered_by":
+		return &o.TriggeredBy
+	case "fixed":
+		return &o.Fixed
+	case "duration":
+		return &o.Duration
+	case "is_in_effect":
+		return &o.IsInEffect
+	case "start_notification_sent":
+		return &o.StartNotificationSent
+	case "author":
+		return &o.Author
+	case "comment":
+		return &o.Comment
+	}
+	return nil
+}
+
+func (o *ServiceDowntime) fieldRef(key string) *string {
+	switch key {
+	case "host_name":
+		return &o.HostName
+	case "service_description":
+		return &o.ServiceDescription
+	case "downtime_id":
+		return &o.DowntimeID
+	case "comment_id":
+		return &o.CommentID
+	case "entry_time":
+		return &o.EntryTime
+	case "start_time":
+		return &o.StartTime
+	case "flex_downtime_start":
+		return &o.FlexDowntimeStart
+	case "end_time":
+		return &o.EndTime
+	case "triggered_by":
+		return &o.TriggeredBy
+	case "fixed":
+		return &o.Fixed
+	case "duration":
+		return &o.Duration
+	case "is_in_effect":
+		return &o.IsInEffect
+	case "start_notification_sent":
+		return &o.StartNotificationSent
+	case "author":
+		return &o.Author
+	case "comment":
+		return &o.Comment
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// maxStatusLine bounds the length of a status.dat line. Nagios escapes the newlines of plugin
+// output, so a single line holds a whole long_plugin_output.
+const maxStatusLine = 16 * 1024 * 1024
+
+// maxInternedValue is the longest value shared between objects rather than copied for each.
+// States, flags, periods and timestamps repeat across thousands of objects.
+const maxInternedValue = 32
+
+// statusParser reads status.dat a line at a time. Blocks open with a "<type> {" line and close
+// with a line holding only "}", so braces inside values need no escaping.
+type statusParser struct {
+	scanner *bufio.Scanner
+	line    int
+	strings map[string]string
+}
+
+func newStatusParser(r io.Reader) *statusParser {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxStatusLine)
+	return &statusParser{scanner: scanner, strings: make(map[string]string)}
+}
+
+// intern returns b as a string, shared with the earlier occurrences of the same bytes
+func (p *statusParser) intern(b []byte) string {
+	if s, ok := p.strings[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	p.strings[s] = s
+	return s
+}
+
+func (p *statusParser) value(b []byte) string {
+	if len(b) > maxInternedValue {
+		return string(b)
+	}
+	return p.intern(b)
+}
+
+func (p *statusParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("status.dat line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// parse reads every block into data
+func (p *statusParser) parse(data *StatusData) error {
+	var (
+		inBlock bool
+		kind    string
+		// obj is nil inside blocks of types that are not served
+		obj settableType
+	)
+
+	for p.scanner.Scan() {
+		p.line++
+		line := bytes.TrimSpace(p.scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		if !inBlock {
+			if !bytes.HasSuffix(line, []byte("{")) {
+				return p.errorf("expected the start of a block, got %.80q", line)
+			}
+			kind = string(bytes.TrimSpace(line[:len(line)-1]))
+			obj = data.newObject(kind)
+			inBlock = true
+			continue
+		}
+
+		if len(line) == 1 && line[0] == '}' {
+			if s, ok := obj.(*ServiceStatus); ok {
+				data.HostServices[s.HostName] = append(data.HostServices[s.HostName], s)
+			}
+			inBlock, obj = false, nil
+			continue
+		}
+
+		eq := bytes.IndexByte(line, '=')
+		if eq <= 0 {
+			return p.errorf("expected key=value in %s block, got %.80q", kind, line)
+		}
+		if obj == nil {
+			continue
+		}
+
+		key, value := line[:eq], line[eq+1:]
+		if key[0] == '_' {
+			// Custom variables are written as _NAME=<modified>;<value>
+			if semi := bytes.IndexByte(value, ';'); semi != -1 {
+				value = value[semi+1:]
+			}
+			obj.setCustomVariable(p.intern(key[1:]), p.value(value))
+			continue
+		}
+		// Keys added by newer Nagios versions are skipped
+		obj.setField(p.intern(key), p.value(value))
+	}
+
+	if err := p.scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			p.line++
+			return p.errorf("line longer than %d bytes", maxStatusLine)
+		}
+		return err
+	}
+	if inBlock {
+		return p.errorf("unexpected end of file in %s block", kind)
+	}
+	return nil
+}
+
+// newObject returns a new object for a block of type kind, added to data, or nil when kind
+// is not served
+func (d *StatusData) newObject(kind string) settableType {
+	switch kind {
+	case "info":
+		d.Info = &InfoStatus{}
+		return d.Info
+	case "programstatus":
+		d.Program = &ProgramStatus{}
+		return d.Program
+	case "contactstatus":
+		obj := &ContactStatus{}
+		d.Contacts = append(d.Contacts, obj)
+		return obj
+	case "hoststatus":
+		obj := &HostStatus{}
+		d.Hosts = append(d.Hosts, obj)
+		return obj
+	case "servicestatus":
+		obj := &ServiceStatus{}
+		d.Services = append(d.Services, obj)
+		return obj
+	case "hostcomment":
+		obj := &HostComment{}
+		d.HostComments = append(d.HostComments, obj)
+		return obj
+	case "servicecomment":
+		obj := &ServiceComment{}
+		d.ServiceComments = append(d.ServiceComments, obj)
+		return obj
+	case "hostdowntime":
+		obj := &HostDowntime{}
+		d.HostDowntimes = append(d.HostDowntimes, obj)
+		return obj
+	case "servicedowntime":
+		obj := &ServiceDowntime{}
+		d.ServiceDowntimes = append(d.ServiceDowntimes, obj)
+		return obj
+	}
+	return nil
+}
+
+func refreshStatusData(fh io.Reader) (*StatusData, error) {
+	data := NewStatusData()
+	if err := newStatusParser(fh).parse(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cheekybits/is"
+)
+
+func TestStatusParser(t *testing.T) {
+	is := is.New(t)
+
+	data, err := refreshStatusData(strings.NewReader(`# NAGIOS STATUS FILE
+hoststatus {
+    host_name=web01
+    plugin_output=JSON OK - {"status": "up"}
+    long_plugin_output=}\n{
+    added_in_nagios_5=1
+    _OWNER=0;ops;team
+    _BARE=value
+    }
+
+unknownstatus {
+    some_key=}
+    }
+
+servicestatus {
+	host_name=web01
+	service_description=HTTP
+	plugin_output=key=value }
+	}
+`))
+	is.NoErr(err)
+
+	is.Equal(len(data.Hosts), 1)
+	h := data.Hosts[0]
+	is.Equal(h.HostName, "web01")
+	is.Equal(h.PluginOutput, `JSON OK - {"status": "up"}`)
+	is.Equal(h.LongPluginOutput, `}\n{`)
+	is.Equal(h.CustomVariables, map[string]string{"OWNER": "ops;team", "BARE": "value"})
+
+	is.Equal(len(data.Services), 1)
+	is.Equal(data.Services[0].PluginOutput, "key=value }")
+	is.Equal(data.HostServices["web01"], data.Services)
+}
+
+func TestStatusParserErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{name: "line outside block", input: "hoststatus {\n}\nhost_name=web01\n", err: `status.dat line 3: expected the start of a block, got "host_name=web01"`},
+		{name: "missing key", input: "hoststatus {\n    host_name=web01\n    =1\n}\n", err: `status.dat line 3: expected key=value in hoststatus block, got "=1"`},
+		{name: "missing equals", input: "\n\nservicestatus {\n    current_state\n}\n", err: `status.dat line 4: expected key=value in servicestatus block, got "current_state"`},
+		{name: "truncated", input: "info {\n    version=4.2.4\n", err: "status.dat line 2: unexpected end of file in info block"},
+		{name: "line too long", input: "info {\n    version=" + strings.Repeat("x", maxStatusLine) + "\n}\n", err: fmt.Sprintf("status.dat line 2: line longer than %d bytes", maxStatusLine)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			_, err := refreshStatusData(strings.NewReader(tt.input))
+			is.Err(err)
+			is.Equal(err.Error(), tt.err)
+		})
+	}
+}
+
+// TestFieldRef checks that the fieldRef methods cover every string field by its JSON tag
+func TestFieldRef(t *testing.T) {
+	objects := []interface {
+		fieldRef(string) *string
+	}{
+		&ContactStatus{}, &HostStatus{}, &InfoStatus{}, &ProgramStatus{}, &ServiceStatus{},
+		&HostComment{}, &ServiceComment{}, &HostDowntime{}, &ServiceDowntime{},
+	}
+
+	for _, obj := range objects {
+		val := reflect.ValueOf(obj).Elem()
+		t.Run(val.Type().Name(), func(t *testing.T) {
+			is := is.New(t)
+			for i := 0; i < val.NumField(); i++ {
+				if val.Field(i).Kind() != reflect.String {
+					continue
+				}
+				key := strings.Split(val.Type().Field(i).Tag.Get("json"), ",")[0]
+				is.Equal(obj.fieldRef(key), val.Field(i).Addr().Interface())
+			}
+			is.Nil(obj.fieldRef("no_such_field"))
+		})
+	}
+}
+
+// generateStatusDat returns a status.dat with every field set for hosts with services each
+func generateStatusDat(hosts, services int) []byte {
+	var b bytes.Buffer
+	block := func(kind string, obj interface{}, values map[string]string, n int) {
+		fmt.Fprintf(&b, "%s {\n", kind)
+		typ := reflect.TypeOf(obj).Elem()
+		for i := 0; i < typ.NumField(); i++ {
+			key := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+			if typ.Field(i).Type.Kind() != reflect.String {
+				continue
+			}
+			value, ok := values[key]
+			if !ok {
+				value = fmt.Sprint(n % 3)
+			}
+			fmt.Fprintf(&b, "\t%s=%s\n", key, value)
+		}
+		fmt.Fprintf(&b, "\t_SERIAL=0;%d\n\t}\n\n", n)
+	}
+
+	block("info", &InfoStatus{}, map[string]string{"version": "4.4.6"}, 0)
+	block("programstatus", &ProgramStatus{}, nil, 0)
+	for h := 0; h < hosts; h++ {
+		host := fmt.Sprintf("host%05d.example.com", h)
+		block("hoststatus", &HostStatus{}, map[string]string{
+			"host_name":     host,
+			"check_command": "check-host-alive",
+			"last_check":    fmt.Sprint(1484082873 + h%300),
+			"plugin_output": fmt.Sprintf("PING OK - Packet loss = 0%%, RTA = 0.%02d ms", h%100),
+		}, h)
+		for s := 0; s < services; s++ {
+			block("servicestatus", &ServiceStatus{}, map[string]string{
+				"host_name":           host,
+				"service_description": fmt.Sprintf("Service %d", s),
+				"check_command":       "check_nrpe!check_service",
+				"last_check":          fmt.Sprint(1484082873 + (h*services+s)%300),
+				"plugin_output":       fmt.Sprintf("OK - {\"host\": %d, \"service\": %d}", h, s),
+				"performance_data":    fmt.Sprintf("time=0.%03ds;1;2;0 size=%dB;;;0", s, h*s),
+			}, h+s)
+		}
+	}
+	return b.Bytes()
+}
+
+// BenchmarkRefreshStatusData parses the status.dat of 1000 hosts with 40 services each
+func BenchmarkRefreshStatusData(b *testing.B) {
+	dat := generateStatusDat(1000, 40)
+	b.SetBytes(int64(len(dat)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		data, err := refreshStatusData(bytes.NewReader(dat))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(data.Services) != 40000 {
+			b.Fatalf("parsed %d services", len(data.Services))
+		}
+	}
+}
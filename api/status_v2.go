@@ -0,0 +1,348 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Codes of the check types and acknowledgement types by name, next to the states and state types
+// of bulk_command.go
+var (
+	checkTypes           = map[string]string{"ACTIVE": "0", "PASSIVE": "1"}
+	acknowledgementTypes = map[string]string{"NONE": "0", "NORMAL": "1", "STICKY": "2"}
+)
+
+// Timestamp is a status.dat time. It is rendered as an object holding the time in RFC3339 and
+// as seconds since the epoch, or as null when the time was never set.
+type Timestamp struct {
+	time.Time
+}
+
+type timestampJSON struct {
+	RFC3339 string `json:"rfc3339"`
+	Epoch   int64  `json:"epoch"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(timestampJSON{RFC3339: t.UTC().Format(time.RFC3339), Epoch: t.Unix()})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *Timestamp) UnmarshalJSON(b []byte) error {
+	var v *timestampJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*t = Timestamp{}
+	if v != nil {
+		*t = epochTimestamp(v.Epoch)
+	}
+	return nil
+}
+
+func epochTimestamp(epoch int64) Timestamp {
+	if epoch <= 0 {
+		return Timestamp{}
+	}
+	return Timestamp{time.Unix(epoch, 0).UTC()}
+}
+
+// The parse helpers convert status.dat values, which nagios always writes well formed. A value
+// missing from an older status.dat converts to the zero value.
+
+func parseTimestamp(s string) Timestamp {
+	epoch, _ := strconv.ParseInt(s, 10, 64)
+	return epochTimestamp(epoch)
+}
+
+func parseInt(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+func parseBool(s string) bool {
+	return s == "1"
+}
+
+// parseEnum returns the name of the code s in names, or s itself when it has no name
+func parseEnum(names map[string]string, s string) string {
+	for name, code := range names {
+		if code == s {
+			return name
+		}
+	}
+	return s
+}
+
+// checkStatusV2 holds the fields hoststatus and servicestatus have in common
+type checkStatusV2 struct {
+	HostName                   string            `json:"host_name"`
+	CurrentState               string            `json:"current_state"`
+	LastHardState              string            `json:"last_hard_state"`
+	StateType                  string            `json:"state_type"`
+	CheckType                  string            `json:"check_type"`
+	AcknowledgementType        string            `json:"acknowledgement_type"`
+	PluginOutput               string            `json:"plugin_output"`
+	LongPluginOutput           string            `json:"long_plugin_output"`
+	PerformanceData            string            `json:"performance_data"`
+	CheckCommand               string            `json:"check_command"`
+	CheckPeriod                string            `json:"check_period"`
+	NotificationPeriod         string            `json:"notification_period"`
+	EventHandler               string            `json:"event_handler"`
+	CurrentAttempt             int               `json:"current_attempt"`
+	MaxAttempts                int               `json:"max_attempts"`
+	CheckOptions               int               `json:"check_options"`
+	ModifiedAttributes         int               `json:"modified_attributes"`
+	ScheduledDowntimeDepth     int               `json:"scheduled_downtime_depth"`
+	CurrentNotificationNumber  int               `json:"current_notification_number"`
+	CurrentEventID             int               `json:"current_event_id"`
+	LastEventID                int               `json:"last_event_id"`
+	CurrentProblemID           int               `json:"current_problem_id"`
+	LastProblemID              int               `json:"last_problem_id"`
+	CurrentNotificationID      int               `json:"current_notification_id"`
+	CheckInterval              float64           `json:"check_interval"`
+	RetryInterval              float64           `json:"retry_interval"`
+	CheckExecutionTime         float64           `json:"check_execution_time"`
+	CheckLatency               float64           `json:"check_latency"`
+	PercentStateChange         float64           `json:"percent_state_change"`
+	HasBeenChecked             bool              `json:"has_been_checked"`
+	ShouldBeScheduled          bool              `json:"should_be_scheduled"`
+	ActiveChecksEnabled        bool              `json:"active_checks_enabled"`
+	PassiveChecksEnabled       bool              `json:"passive_checks_enabled"`
+	EventHandlerEnabled        bool              `json:"event_handler_enabled"`
+	FlapDetectionEnabled       bool              `json:"flap_detection_enabled"`
+	IsFlapping                 bool              `json:"is_flapping"`
+	NotificationsEnabled       bool              `json:"notifications_enabled"`
+	NoMoreNotifications        bool              `json:"no_more_notifications"`
+	ProblemHasBeenAcknowledged bool              `json:"problem_has_been_acknowledged"`
+	ProcessPerformanceData     bool              `json:"process_performance_data"`
+	Obsess                     bool              `json:"obsess"`
+	LastCheck                  Timestamp         `json:"last_check"`
+	NextCheck                  Timestamp         `json:"next_check"`
+	LastStateChange            Timestamp         `json:"last_state_change"`
+	LastHardStateChange        Timestamp         `json:"last_hard_state_change"`
+	LastNotification           Timestamp         `json:"last_notification"`
+	NextNotification           Timestamp         `json:"next_notification"`
+	LastUpdate                 Timestamp         `json:"last_update"`
+	CustomVariables            map[string]string `json:"custom_variables,omitempty"`
+}
+
+// HostStatusV2 is the hoststatus served by the v2 API, with typed fields
+type HostStatusV2 struct {
+	checkStatusV2
+	LastTimeUp          Timestamp `json:"last_time_up"`
+	LastTimeDown        Timestamp `json:"last_time_down"`
+	LastTimeUnreachable Timestamp `json:"last_time_unreachable"`
+}
+
+// ServiceStatusV2 is the servicestatus served by the v2 API, with typed fields
+type ServiceStatusV2 struct {
+	checkStatusV2
+	ServiceDescription string    `json:"service_description"`
+	LastTimeOk         Timestamp `json:"last_time_ok"`
+	LastTimeWarning    Timestamp `json:"last_time_warning"`
+	LastTimeCritical   Timestamp `json:"last_time_critical"`
+	LastTimeUnknown    Timestamp `json:"last_time_unknown"`
+}
+
+// V2 returns the typed hoststatus
+func (h *HostStatus) V2() *HostStatusV2 {
+	return &HostStatusV2{
+		checkStatusV2: checkStatusV2{
+			HostName:                   h.HostName,
+			CurrentState:               parseEnum(hostStates, h.CurrentState),
+			LastHardState:              parseEnum(hostStates, h.LastHardState),
+			StateType:                  parseEnum(stateTypes, h.StateType),
+			CheckType:                  parseEnum(checkTypes, h.CheckType),
+			AcknowledgementType:        parseEnum(acknowledgementTypes, h.AcknowledgementType),
+			PluginOutput:               h.PluginOutput,
+			LongPluginOutput:           h.LongPluginOutput,
+			PerformanceData:            h.PerformanceData,
+			CheckCommand:               h.CheckCommand,
+			CheckPeriod:                h.CheckPeriod,
+			NotificationPeriod:         h.NotificationPeriod,
+			EventHandler:               h.EventHandler,
+			CurrentAttempt:             parseInt(h.CurrentAttempt),
+			MaxAttempts:                parseInt(h.MaxAttempts),
+			CheckOptions:               parseInt(h.CheckOptions),
+			ModifiedAttributes:         parseInt(h.ModifiedAttributes),
+			ScheduledDowntimeDepth:     parseInt(h.ScheduledDowntimeDepth),
+			CurrentNotificationNumber:  parseInt(h.CurrentNotificationNumber),
+			CurrentEventID:             parseInt(h.CurrentEventID),
+			LastEventID:                parseInt(h.LastEventID),
+			CurrentProblemID:           parseInt(h.CurrentProblemID),
+			LastProblemID:              parseInt(h.LastProblemID),
+			CurrentNotificationID:      parseInt(h.CurrentNotificationID),
+			CheckInterval:              parseFloat(h.CheckInterval),
+			RetryInterval:              parseFloat(h.RetryInterval),
+			CheckExecutionTime:         parseFloat(h.CheckExecutionTime),
+			CheckLatency:               parseFloat(h.CheckLatency),
+			PercentStateChange:         parseFloat(h.PercentStateChange),
+			HasBeenChecked:             parseBool(h.HasBeenChecked),
+			ShouldBeScheduled:          parseBool(h.ShouldBeScheduled),
+			ActiveChecksEnabled:        parseBool(h.ActiveChecksEnabled),
+			PassiveChecksEnabled:       parseBool(h.PassiveChecksEnabled),
+			EventHandlerEnabled:        parseBool(h.EventHandlerEnabled),
+			FlapDetectionEnabled:       parseBool(h.FlapDetectionEnabled),
+			IsFlapping:                 parseBool(h.IsFlapping),
+			NotificationsEnabled:       parseBool(h.NotificationsEnabled),
+			NoMoreNotifications:        parseBool(h.NoMoreNotifications),
+			ProblemHasBeenAcknowledged: parseBool(h.ProblemHasBeenAcknowledged),
+			ProcessPerformanceData:     parseBool(h.ProcessPerformanceData),
+			Obsess:                     parseBool(h.Obsess),
+			LastCheck:                  parseTimestamp(h.LastCheck),
+			NextCheck:                  parseTimestamp(h.NextCheck),
+			LastStateChange:            parseTimestamp(h.LastStateChange),
+			LastHardStateChange:        parseTimestamp(h.LastHardStateChange),
+			LastNotification:           parseTimestamp(h.LastNotification),
+			NextNotification:           parseTimestamp(h.NextNotification),
+			LastUpdate:                 parseTimestamp(h.LastUpdate),
+			CustomVariables:            h.CustomVariables,
+		},
+		LastTimeUp:          parseTimestamp(h.LastTimeUp),
+		LastTimeDown:        parseTimestamp(h.LastTimeDown),
+		LastTimeUnreachable: parseTimestamp(h.LastTimeUnreachable),
+	}
+}
+
+// V2 returns the typed servicestatus
+func (s *ServiceStatus) V2() *ServiceStatusV2 {
+	return &ServiceStatusV2{
+		checkStatusV2: checkStatusV2{
+			HostName:                   s.HostName,
+			CurrentState:               parseEnum(serviceStates, s.CurrentState),
+			LastHardState:              parseEnum(serviceStates, s.LastHardState),
+			StateType:                  parseEnum(stateTypes, s.StateType),
+			CheckType:                  parseEnum(checkTypes, s.CheckType),
+			AcknowledgementType:        parseEnum(acknowledgementTypes, s.AcknowledgementType),
+			PluginOutput:               s.PluginOutput,
+			LongPluginOutput:           s.LongPluginOutput,
+			PerformanceData:            s.PerformanceData,
+			CheckCommand:               s.CheckCommand,
+			CheckPeriod:                s.CheckPeriod,
+			NotificationPeriod:         s.NotificationPeriod,
+			EventHandler:               s.EventHandler,
+			CurrentAttempt:             parseInt(s.CurrentAttempt),
+			MaxAttempts:                parseInt(s.MaxAttempts),
+			CheckOptions:               parseInt(s.CheckOptions),
+			ModifiedAttributes:         parseInt(s.ModifiedAttributes),
+			ScheduledDowntimeDepth:     parseInt(s.ScheduledDowntimeDepth),
+			CurrentNotificationNumber:  parseInt(s.CurrentNotificationNumber),
+			CurrentEventID:             parseInt(s.CurrentEventID),
+			LastEventID:                parseInt(s.LastEventID),
+			CurrentProblemID:           parseInt(s.CurrentProblemID),
+			LastProblemID:              parseInt(s.LastProblemID),
+			CurrentNotificationID:      parseInt(s.CurrentNotificationID),
+			CheckInterval:              parseFloat(s.CheckInterval),
+			RetryInterval:              parseFloat(s.RetryInterval),
+			CheckExecutionTime:         parseFloat(s.CheckExecutionTime),
+			CheckLatency:               parseFloat(s.CheckLatency),
+			PercentStateChange:         parseFloat(s.PercentStateChange),
+			HasBeenChecked:             parseBool(s.HasBeenChecked),
+			ShouldBeScheduled:          parseBool(s.ShouldBeScheduled),
+			ActiveChecksEnabled:        parseBool(s.ActiveChecksEnabled),
+			PassiveChecksEnabled:       parseBool(s.PassiveChecksEnabled),
+			EventHandlerEnabled:        parseBool(s.EventHandlerEnabled),
+			FlapDetectionEnabled:       parseBool(s.FlapDetectionEnabled),
+			IsFlapping:                 parseBool(s.IsFlapping),
+			NotificationsEnabled:       parseBool(s.NotificationsEnabled),
+			NoMoreNotifications:        parseBool(s.NoMoreNotifications),
+			ProblemHasBeenAcknowledged: parseBool(s.ProblemHasBeenAcknowledged),
+			ProcessPerformanceData:     parseBool(s.ProcessPerformanceData),
+			Obsess:                     parseBool(s.Obsess),
+			LastCheck:                  parseTimestamp(s.LastCheck),
+			NextCheck:                  parseTimestamp(s.NextCheck),
+			LastStateChange:            parseTimestamp(s.LastStateChange),
+			LastHardStateChange:        parseTimestamp(s.LastHardStateChange),
+			LastNotification:           parseTimestamp(s.LastNotification),
+			NextNotification:           parseTimestamp(s.NextNotification),
+			LastUpdate:                 parseTimestamp(s.LastUpdate),
+			CustomVariables:            s.CustomVariables,
+		},
+		ServiceDescription: s.ServiceDescription,
+		LastTimeOk:         parseTimestamp(s.LastTimeOk),
+		LastTimeWarning:    parseTimestamp(s.LastTimeWarning),
+		LastTimeCritical:   parseTimestamp(s.LastTimeCritical),
+		LastTimeUnknown:    parseTimestamp(s.LastTimeUnknown),
+	}
+}
+
+func hostStatusV2(hosts []*HostStatus) []*HostStatusV2 {
+	list := []*HostStatusV2{}
+	for _, h := range hosts {
+		list = append(list, h.V2())
+	}
+	return list
+}
+
+func serviceStatusV2(services []*ServiceStatus) []*ServiceStatusV2 {
+	list := []*ServiceStatusV2{}
+	for _, s := range services {
+		list = append(list, s.V2())
+	}
+	return list
+}
+
+// HandleGetAllHostStatusV2 returns the typed hoststatus of all hosts
+// GET: /v2/hoststatus
+func (a *Api) HandleGetAllHostStatusV2(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hostStatusV2(a.visibleHostStatus(r)))
+}
+
+// HandleGetHostStatusForHostV2 returns the typed hoststatus of the requested host
+// GET: /v2/hoststatus/<host>
+func (a *Api) HandleGetHostStatusForHostV2(w http.ResponseWriter, r *http.Request) {
+	item := a.requestedHostStatus(w, r)
+	if item == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item.V2())
+}
+
+// HandleGetServiceStatusV2 returns the typed servicestatus of all services
+// GET: /v2/servicestatus
+func (a *Api) HandleGetServiceStatusV2(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(serviceStatusV2(a.visibleServiceStatus(r, "")))
+}
+
+// HandleGetServiceStatusForServiceV2 returns the typed servicestatus of the requested service
+// on every host
+// GET: /v2/servicestatus/<service>
+func (a *Api) HandleGetServiceStatusForServiceV2(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	service, ok := vars["service"]
+	if !ok {
+		http.Error(w, "Could not find service to lookup", 400)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(serviceStatusV2(a.visibleServiceStatus(r, service)))
+}
+
+// HandleGetServicesForHostV2 returns the typed servicestatus of the services of the given host
+// GET: /v2/host/<hostname>/services
+func (a *Api) HandleGetServicesForHostV2(w http.ResponseWriter, r *http.Request) {
+	services, ok := a.requestedHostServices(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(serviceStatusV2(services))
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sebor/nagios-api/auth"
+	"github.com/cheekybits/is"
+)
+
+func TestTimestamp(t *testing.T) {
+	is := is.New(t)
+
+	b, err := json.Marshal(parseTimestamp("1484082873"))
+	is.NoErr(err)
+	is.Equal(string(b), `{"rfc3339":"2017-01-10T21:14:33Z","epoch":1484082873}`)
+
+	var ts Timestamp
+	is.NoErr(json.Unmarshal(b, &ts))
+	is.True(ts.Equal(time.Unix(1484082873, 0)))
+
+	for _, never := range []string{"0", ""} {
+		b, err = json.Marshal(parseTimestamp(never))
+		is.NoErr(err)
+		is.Equal(string(b), "null")
+	}
+}
+
+func TestParseEnum(t *testing.T) {
+	is := is.New(t)
+	is.Equal(parseEnum(hostStates, "1"), "DOWN")
+	is.Equal(parseEnum(serviceStates, "3"), "UNKNOWN")
+	is.Equal(parseEnum(serviceStates, "4"), "4")
+	is.Equal(parseEnum(stateTypes, ""), "")
+}
+
+func TestHostStatusV2(t *testing.T) {
+	is := is.New(t)
+	api := newAuthorizedTestApi(t)
+
+	w := httptest.NewRecorder()
+	api.router.ServeHTTP(w, auth.WithPrincipal(httptest.NewRequest("GET", "/v2/hoststatus/web01", nil), "nagiosadmin"))
+	is.Equal(w.Code, 200)
+
+	var h HostStatusV2
+	is.NoErr(json.NewDecoder(w.Body).Decode(&h))
+	is.Equal(h.HostName, "web01")
+	is.Equal(h.CurrentState, "UP")
+	is.Equal(h.StateType, "HARD")
+	is.Equal(h.CurrentAttempt, 1)
+	is.Equal(h.MaxAttempts, 10)
+	is.Equal(h.CheckExecutionTime, 0.011)
+	is.True(h.HasBeenChecked)
+	is.Equal(h.LastCheck.Unix(), 1484082873)
+	is.Equal(h.NextCheck.Unix(), 1484083173)
+	is.True(h.LastTimeDown.IsZero())
+
+	// The unversioned route keeps the strings of status.dat
+	w = httptest.NewRecorder()
+	api.router.ServeHTTP(w, auth.WithPrincipal(httptest.NewRequest("GET", "/hoststatus/web01", nil), "nagiosadmin"))
+	var v1 map[string]interface{}
+	is.NoErr(json.NewDecoder(w.Body).Decode(&v1))
+	is.Equal(v1["current_state"], "0")
+	is.Equal(v1["last_check"], "1484082873")
+
+	w = httptest.NewRecorder()
+	api.router.ServeHTTP(w, auth.WithPrincipal(httptest.NewRequest("GET", "/v2/hoststatus/web02", nil), "nagiosadmin"))
+	is.Equal(w.Code, 404)
+}
+
+func TestServiceStatusV2(t *testing.T) {
+	api := newAuthorizedTestApi(t)
+
+	tests := []struct {
+		user  string
+		path  string
+		count int
+	}{
+		{user: "nagiosadmin", path: "/v2/servicestatus", count: 1},
+		{user: "nagiosadmin", path: "/v2/servicestatus/HTTP", count: 1},
+		{user: "nagiosadmin", path: "/v2/servicestatus/SSH", count: 0},
+		{user: "alice", path: "/v2/host/web01/services", count: 1},
+		{user: "jason", path: "/v2/servicestatus", count: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.user+" "+tt.path, func(t *testing.T) {
+			is := is.New(t)
+
+			w := httptest.NewRecorder()
+			api.router.ServeHTTP(w, auth.WithPrincipal(httptest.NewRequest("GET", tt.path, nil), tt.user))
+			is.Equal(w.Code, 200)
+
+			var services []*ServiceStatusV2
+			is.NoErr(json.NewDecoder(w.Body).Decode(&services))
+			is.Equal(len(services), tt.count)
+			if tt.count == 0 {
+				return
+			}
+
+			s := services[0]
+			is.Equal(s.ServiceDescription, "HTTP")
+			is.Equal(s.CurrentState, "CRITICAL")
+			is.Equal(s.LastHardState, "OK")
+			is.Equal(s.StateType, "SOFT")
+			is.Equal(s.CheckType, "ACTIVE")
+			is.Equal(s.AcknowledgementType, "NORMAL")
+			is.True(s.ProblemHasBeenAcknowledged)
+			is.Equal(s.CurrentAttempt, 2)
+			is.Equal(s.PercentStateChange, 6.25)
+			is.Equal(s.LastCheck.Format(time.RFC3339), "2017-01-10T21:14:33Z")
+		})
+	}
+}
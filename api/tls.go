@@ -0,0 +1,123 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// tlsFiles serves a certificate and key pair, and optionally verifies client certificates
+// against a CA bundle. The files are read again when they change so rotated certificates are
+// picked up without a restart.
+type tlsFiles struct {
+	mutex             sync.Mutex
+	certFile          string
+	keyFile           string
+	clientCAFile      string
+	requireClientCert bool
+	// modTimes of the certificate, key and CA bundle when they were read last
+	modTimes  [3]time.Time
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+func loadTLSFiles(certFile, keyFile, clientCAFile string, requireClientCert bool) (*tlsFiles, error) {
+	if requireClientCert && clientCAFile == "" {
+		return nil, errors.New("a client CA bundle is required to verify client certificates")
+	}
+
+	t := &tlsFiles{certFile: certFile, keyFile: keyFile, clientCAFile: clientCAFile, requireClientCert: requireClientCert}
+	if err := t.reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// reload reads the files again when one of them was modified since they were last read
+func (t *tlsFiles) reload() error {
+	var modTimes [3]time.Time
+	for i, path := range []string{t.certFile, t.keyFile, t.clientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	if modTimes == t.modTimes {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(t.certFile, t.keyFile)
+	if err != nil {
+		return err
+	}
+
+	var clientCAs *x509.CertPool
+	if t.clientCAFile != "" {
+		pem, err := ioutil.ReadFile(t.clientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errors.New(t.clientCAFile + ": no PEM certificates found")
+		}
+	}
+
+	t.cert, t.clientCAs, t.modTimes = &cert, clientCAs, modTimes
+	return nil
+}
+
+// current returns the certificate and client CAs read last, after checking the files for changes
+func (t *tlsFiles) current() (*tls.Certificate, *x509.CertPool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if err := t.reload(); err != nil {
+		// Keep serving the certificate read last until the files are complete again
+		log.Println("Unable to reload TLS certificates: ", err)
+	}
+	return t.cert, t.clientCAs
+}
+
+// config returns the server TLS configuration, resolving certificate and client CAs for each handshake
+func (t *tlsFiles) config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, clientCAs := t.current()
+			c := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+			}
+			if clientCAs != nil {
+				c.ClientCAs = clientCAs
+				c.ClientAuth = tls.VerifyClientCertIfGiven
+				if t.requireClientCert {
+					c.ClientAuth = tls.RequireAndVerifyClientCert
+				}
+			}
+			return c, nil
+		},
+	}
+}
+
+// EnableTLS serves HTTPS with the certificate and key in certFile and keyFile. With clientCAFile
+// client certificates are verified against its CA bundle, and required with requireClientCert.
+// The files are reloaded when they change.
+func (a *Api) EnableTLS(certFile, keyFile, clientCAFile string, requireClientCert bool) error {
+	t, err := loadTLSFiles(certFile, keyFile, clientCAFile, requireClientCert)
+	if err != nil {
+		return err
+	}
+	a.tls = t
+	return nil
+}
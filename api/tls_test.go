@@ -0,0 +1,141 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+)
+
+// testCertificate is a certificate with its key, signed by parent or self-signed when parent is nil
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCertificate(t *testing.T, name string, serial int64, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"Example"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{cert: cert, key: key}
+}
+
+// write stores the certificate and key as PEM files in dir and returns their paths
+func (c *testCertificate) write(t *testing.T, dir, name string) (string, string) {
+	keyDer, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (c *testCertificate) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}
+}
+
+func TestTLSFiles(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "nagios-api")
+	is.NoErr(err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCertificate(t, "Example CA", 1, nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newTestCertificate(t, "nagios-api", 2, ca).write(t, dir, "server")
+	client := newTestCertificate(t, "jason", 3, ca)
+
+	_, err = loadTLSFiles(certFile, keyFile, "", true)
+	is.Err(err)
+
+	files, err := loadTLSFiles(certFile, keyFile, caFile, false)
+	is.NoErr(err)
+
+	var principal string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = ""
+		if len(r.TLS.VerifiedChains) > 0 {
+			principal = r.TLS.VerifiedChains[0][0].Subject.CommonName
+		}
+	}))
+	server.TLS = files.config()
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(certs ...tls.Certificate) *http.Response {
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+		resp, err := c.Get(server.URL)
+		is.NoErr(err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get(client.tlsCertificate())
+	is.Equal(principal, "jason")
+	is.Equal(resp.TLS.PeerCertificates[0].SerialNumber.Int64(), 2)
+
+	get()
+	is.Equal(principal, "")
+
+	// A rotated certificate is served from the next handshake on
+	newTestCertificate(t, "nagios-api", 4, ca).write(t, dir, "server")
+	later := time.Now().Add(time.Minute)
+	is.NoErr(os.Chtimes(certFile, later, later))
+	resp = get()
+	is.Equal(resp.TLS.PeerCertificates[0].SerialNumber.Int64(), 4)
+
+	// A half-written rotation keeps the previous certificate
+	is.NoErr(ioutil.WriteFile(keyFile, []byte("incomplete"), 0600))
+	resp = get()
+	is.Equal(resp.TLS.PeerCertificates[0].SerialNumber.Int64(), 4)
+}
@@ -3,14 +3,68 @@ package api
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// text marks the trailing free-text argument of a command (comment, plugin output).
+// Nagios reads it up to the end of the line, so unlike other arguments it may contain semicolons.
+type text string
+
+// buildCommand renders an external command line from its name and arguments. Arguments are
+// rejected when they contain a line break, which would start a new command, or a semicolon
+// anywhere Nagios would split on it.
+func buildCommand(name string, args ...interface{}) (string, error) {
+	pieces := []string{name}
+	for i, arg := range args {
+		var value string
+		switch v := arg.(type) {
+		case string:
+			value = v
+			if strings.Contains(value, ";") {
+				return "", fmt.Errorf("argument %d of %s must not contain a semicolon", i+1, name)
+			}
+		case text:
+			value = string(v)
+			if i != len(args)-1 && strings.Contains(value, ";") {
+				return "", fmt.Errorf("argument %d of %s must not contain a semicolon", i+1, name)
+			}
+		default:
+			value = fmt.Sprint(v)
+		}
+
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return "", fmt.Errorf("argument %d of %s must not contain a line break", i+1, name)
+		}
+		pieces = append(pieces, value)
+	}
+	return strings.Join(pieces, ";"), nil
+}
+
+// commandLines returns commands as they are written to nagios command file, stamped with the current time
+func commandLines(commands []string) []string {
+	now := time.Now().Unix()
+	lines := make([]string, len(commands))
+	for i, command := range commands {
+		lines[i] = fmt.Sprintf("[%d] %s", now, command)
+	}
+	return lines
+}
+
 // WriteCommand writes command to nagios command file
 func (a *Api) WriteCommand(command string) error {
-	commandToWrite := fmt.Sprintf("[%d] %s\n", time.Now().Unix(), command)
+	return a.WriteCommands([]string{command})
+}
+
+// WriteCommands writes commands to nagios command file in a single append instead of
+// opening the file once per command. Nothing is written when commands is empty.
+func (a *Api) WriteCommands(commands []string) error {
+	if len(commands) == 0 {
+		return nil
+	}
+	commandsToWrite := strings.Join(commandLines(commands), "\n") + "\n"
 
 	f, err := os.OpenFile(a.fileCommand, os.O_APPEND|os.O_WRONLY, 0600)
 	if err != nil {
@@ -19,7 +73,7 @@ func (a *Api) WriteCommand(command string) error {
 	}
 	defer f.Close()
 
-	if _, err = f.WriteString(commandToWrite); err != nil {
+	if _, err = f.WriteString(commandsToWrite); err != nil {
 		log.Error(err)
 		return err
 	}
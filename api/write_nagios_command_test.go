@@ -0,0 +1,53 @@
+package api
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/cheekybits/is"
+)
+
+func TestBuildCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		args    []interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "no arguments", command: "ENABLE_NOTIFICATIONS", want: "ENABLE_NOTIFICATIONS"},
+		{name: "mixed types", command: "ADD_HOST_COMMENT", args: []interface{}{"web01", 1, "jason", text("hello")}, want: "ADD_HOST_COMMENT;web01;1;jason;hello"},
+		{name: "semicolon in trailing text", command: "ADD_HOST_COMMENT", args: []interface{}{"web01", 1, "jason", text("a;b")}, want: "ADD_HOST_COMMENT;web01;1;jason;a;b"},
+		{name: "empty argument", command: "DEL_DOWNTIME_BY_HOST_NAME", args: []interface{}{"web01", "", int64(1484082200)}, want: "DEL_DOWNTIME_BY_HOST_NAME;web01;;1484082200"},
+		{name: "semicolon in plain argument", command: "DISABLE_HOST_CHECK", args: []interface{}{"web01;SHUTDOWN_PROGRAM"}, wantErr: true},
+		{name: "semicolon in text that is not last", command: "TEST", args: []interface{}{text("a;b"), "c"}, wantErr: true},
+		{name: "newline in plain argument", command: "DISABLE_HOST_CHECK", args: []interface{}{"web01\n[0] SHUTDOWN_PROGRAM"}, wantErr: true},
+		{name: "newline in trailing text", command: "ADD_HOST_COMMENT", args: []interface{}{"web01", 1, "jason", text("hi\n[0] SHUTDOWN_PROGRAM")}, wantErr: true},
+		{name: "carriage return", command: "ADD_HOST_COMMENT", args: []interface{}{"web01", 1, "jason", text("hi\r")}, wantErr: true},
+		{name: "nul byte", command: "DISABLE_HOST_CHECK", args: []interface{}{"web01\x00"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			got, err := buildCommand(tt.command, tt.args...)
+			if tt.wantErr {
+				is.Err(err)
+				return
+			}
+			is.NoErr(err)
+			is.Equal(got, tt.want)
+		})
+	}
+}
+
+func TestWriteCommandsEmpty(t *testing.T) {
+	is := is.New(t)
+	api := newTestApi(t)
+
+	is.NoErr(api.WriteCommands(nil))
+	dat, err := ioutil.ReadFile(api.fileCommand)
+	is.NoErr(err)
+	is.Equal(len(dat), 0)
+}
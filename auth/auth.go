@@ -1,10 +1,209 @@
 package auth
 
-import "net/http"
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
 
+	log "github.com/sirupsen/logrus"
+)
+
+type contextKey int
+
+const (
+	principalKey contextKey = iota
+	rolesKey
+	anonymousReadKey
+)
+
+// Authenticator checks the credentials of a request against static API keys, sent in the
+// X-API-Key header or as a bearer token, against JWT bearer tokens and against an htpasswd
+// file for HTTP Basic auth
+type Authenticator struct {
+	// apiKeys maps each key to the name of its principal
+	apiKeys       map[string]string
+	jwt           *jwtVerifier
+	htpasswd      *htpasswdFile
+	clientCerts   bool
+	anonymousRead bool
+}
+
+var (
+	mutex   sync.RWMutex
+	current *Authenticator
+)
+
+// New returns an Authenticator accepting the given API keys, keyed by principal name, and the
+// users of htpasswdPath when not empty. With anonymousRead, handlers wrapped by ReadHandler
+// also serve requests without credentials.
+func New(apiKeys map[string]string, htpasswdPath string, anonymousRead bool) (*Authenticator, error) {
+	a := &Authenticator{apiKeys: make(map[string]string), anonymousRead: anonymousRead}
+	for principal, key := range apiKeys {
+		a.apiKeys[key] = principal
+	}
+
+	if htpasswdPath != "" {
+		h, err := loadHtpasswd(htpasswdPath)
+		if err != nil {
+			return nil, err
+		}
+		a.htpasswd = h
+	}
+	return a, nil
+}
+
+// EnableJWT makes a accept JWT bearer tokens validated following c. The principal and roles
+// of a request are then taken from the claims of its token.
+func (a *Authenticator) EnableJWT(c JWTConfig) error {
+	v, err := newJWTVerifier(c)
+	if err != nil {
+		return err
+	}
+	a.jwt = v
+	return nil
+}
+
+// EnableClientCertificates makes a accept TLS client certificates verified by the server, taking
+// the common name of their subject, or the whole subject without one, as principal
+func (a *Authenticator) EnableClientCertificates() {
+	a.clientCerts = true
+}
+
+// Enabled reports whether any credentials are configured
+func (a *Authenticator) Enabled() bool {
+	return a != nil && (len(a.apiKeys) > 0 || a.jwt != nil || a.htpasswd != nil || a.clientCerts)
+}
+
+// Configure makes AuthHandler and ReadHandler authenticate with a. Until it is called, or
+// when a has no credentials configured, every request is let through.
+func Configure(a *Authenticator) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	current = a
+}
+
+func configured() *Authenticator {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return current
+}
+
+// authenticate returns the principal of the credentials sent with r, and its roles when they
+// came with a JWT. ok is false when no credentials were sent or they are invalid.
+func (a *Authenticator) authenticate(r *http.Request) (principal string, roles []string, ok bool) {
+	key := r.Header.Get("X-API-Key")
+	if bearer := r.Header.Get("Authorization"); key == "" && strings.HasPrefix(bearer, "Bearer ") {
+		key = strings.TrimPrefix(bearer, "Bearer ")
+		if a.jwt != nil && looksLikeJWT(key) {
+			principal, roles, err := a.jwt.verify(key)
+			if err != nil {
+				log.Debugf("Rejected JWT: %s", err)
+				return "", nil, false
+			}
+			return principal, roles, true
+		}
+	}
+	if key != "" {
+		for k, principal := range a.apiKeys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				return principal, nil, true
+			}
+		}
+		return "", nil, false
+	}
+
+	if user, password, hasBasic := r.BasicAuth(); hasBasic && a.htpasswd != nil {
+		if a.htpasswd.verify(user, password) {
+			return user, nil, true
+		}
+		return "", nil, false
+	}
+
+	// Chains are only verified when the server checks client certificates against its CA bundle
+	if a.clientCerts && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		subject := r.TLS.VerifiedChains[0][0].Subject
+		if subject.CommonName != "" {
+			return subject.CommonName, nil, true
+		}
+		return subject.String(), nil, true
+	}
+	return "", nil, false
+}
+
+// handler requires valid credentials, or lets requests without any through when anonymous is set
+func (a *Authenticator) handler(next http.Handler, anonymous bool) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, roles, ok := a.authenticate(r)
+		if ok {
+			next.ServeHTTP(w, WithRoles(WithPrincipal(r, principal), roles))
+			return
+		}
+
+		_, _, hasBasic := r.BasicAuth()
+		hasCredentials := hasBasic || r.Header.Get("X-API-Key") != "" || r.Header.Get("Authorization") != ""
+		if anonymous && !hasCredentials {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), anonymousReadKey, true)))
+			return
+		}
+
+		if a.htpasswd != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="nagios-api"`)
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// AuthHandler requires valid credentials for every request
 func AuthHandler(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
-		next.ServeHTTP(w, r)
+		configured().handler(next, false).ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// ReadHandler is AuthHandler for read-only endpoints: when anonymous read is enabled requests
+// without credentials are served too
+func ReadHandler(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		a := configured()
+		a.handler(next, a != nil && a.anonymousRead).ServeHTTP(w, r)
 	}
 	return http.HandlerFunc(fn)
 }
+
+// WithPrincipal returns a copy of r carrying the name of the authenticated caller
+func WithPrincipal(r *http.Request, principal string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalKey, principal))
+}
+
+// Principal returns the name of the authenticated caller, or "" for anonymous requests
+func Principal(r *http.Request) string {
+	principal, _ := r.Context().Value(principalKey).(string)
+	return principal
+}
+
+// WithRoles returns a copy of r carrying the roles of the authenticated caller
+func WithRoles(r *http.Request, roles []string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), rolesKey, roles))
+}
+
+// Roles returns the roles of the authenticated caller, taken from the groups claim of its JWT
+func Roles(r *http.Request) []string {
+	roles, _ := r.Context().Value(rolesKey).([]string)
+	return roles
+}
+
+// AnonymousRead reports whether r was let through without credentials because anonymous read
+// is enabled
+func AnonymousRead(r *http.Request) bool {
+	anonymous, _ := r.Context().Value(anonymousReadKey).(bool)
+	return anonymous
+}
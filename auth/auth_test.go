@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cheekybits/is"
+)
+
+// echoPrincipal replies with the principal of the request
+var echoPrincipal = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if AnonymousRead(r) {
+		w.Header().Set("X-Anonymous-Read", "true")
+	}
+	w.Write([]byte(Principal(r)))
+})
+
+func TestAuthHandler(t *testing.T) {
+	authenticator, err := New(map[string]string{"ci": "s3cret"}, "testdata/htpasswd", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Configure(authenticator)
+	defer Configure(nil)
+
+	tests := []struct {
+		name      string
+		read      bool
+		header    string
+		value     string
+		user      string
+		password  string
+		code      int
+		principal string
+		anonymous bool
+	}{
+		{name: "api key header", header: "X-API-Key", value: "s3cret", code: 200, principal: "ci"},
+		{name: "bearer token", header: "Authorization", value: "Bearer s3cret", code: 200, principal: "ci"},
+		{name: "wrong api key", header: "X-API-Key", value: "guess", code: 401},
+		{name: "apr1 user", user: "jason", password: "secret", code: 200, principal: "jason"},
+		{name: "bcrypt user", user: "ops", password: "hunter2", code: 200, principal: "ops"},
+		{name: "wrong password", user: "ops", password: "secret", code: 401},
+		{name: "unknown user", user: "eve", password: "secret", code: 401},
+		{name: "anonymous", code: 401},
+		{name: "anonymous read", read: true, code: 200, principal: "", anonymous: true},
+		{name: "read with credentials", read: true, user: "jason", password: "secret", code: 200, principal: "jason"},
+		{name: "read with wrong credentials", read: true, header: "X-API-Key", value: "guess", code: 401},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			if tt.user != "" {
+				r.SetBasicAuth(tt.user, tt.password)
+			}
+
+			handler := AuthHandler(echoPrincipal)
+			if tt.read {
+				handler = ReadHandler(echoPrincipal)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			is.Equal(w.Code, tt.code)
+			if tt.code == 200 {
+				is.Equal(w.Body.String(), tt.principal)
+				is.Equal(w.Header().Get("X-Anonymous-Read") == "true", tt.anonymous)
+			} else {
+				is.Equal(w.Header().Get("WWW-Authenticate"), `Basic realm="nagios-api"`)
+			}
+		})
+	}
+}
+
+func TestAuthHandlerDisabled(t *testing.T) {
+	is := is.New(t)
+
+	for _, authenticator := range []*Authenticator{nil, {}} {
+		Configure(authenticator)
+		w := httptest.NewRecorder()
+		AuthHandler(echoPrincipal).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+		is.Equal(w.Code, 200)
+	}
+	Configure(nil)
+}
+
+func TestAnonymousReadDisabled(t *testing.T) {
+	is := is.New(t)
+	authenticator, err := New(map[string]string{"ci": "s3cret"}, "", false)
+	is.NoErr(err)
+	Configure(authenticator)
+	defer Configure(nil)
+
+	w := httptest.NewRecorder()
+	ReadHandler(echoPrincipal).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	is.Equal(w.Code, 401)
+	is.Equal(w.Header().Get("WWW-Authenticate"), "")
+}
+
+func TestApr1(t *testing.T) {
+	is := is.New(t)
+	is.Equal(apr1("secret", "Pc7Iu1mZ"), "$apr1$Pc7Iu1mZ$I1VGSSNlbX6bq.EKQNJEa1")
+}
+
+func TestLoadHtpasswd(t *testing.T) {
+	is := is.New(t)
+
+	h, err := loadHtpasswd("testdata/htpasswd")
+	is.NoErr(err)
+	is.Equal(len(h.users), 2)
+
+	_, err = loadHtpasswd("testdata/missing")
+	is.Err(err)
+}
+
+func TestClientCertificate(t *testing.T) {
+	is := is.New(t)
+	authenticator, err := New(nil, "", false)
+	is.NoErr(err)
+	authenticator.EnableClientCertificates()
+	Configure(authenticator)
+	defer Configure(nil)
+
+	tests := []struct {
+		subject   pkix.Name
+		principal string
+	}{
+		{subject: pkix.Name{CommonName: "monitoring.example.com"}, principal: "monitoring.example.com"},
+		{subject: pkix.Name{Organization: []string{"Example"}, OrganizationalUnit: []string{"Ops"}}, principal: "OU=Ops,O=Example"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: tt.subject}}}}
+		w := httptest.NewRecorder()
+		AuthHandler(echoPrincipal).ServeHTTP(w, r)
+		is.Equal(w.Code, 200)
+		is.Equal(w.Body.String(), tt.principal)
+	}
+
+	// Certificates the server did not verify are not credentials
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "eve"}}}}
+	w := httptest.NewRecorder()
+	AuthHandler(echoPrincipal).ServeHTTP(w, r)
+	is.Equal(w.Code, 401)
+}
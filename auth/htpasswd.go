@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// htpasswdFile holds the users of an Apache htpasswd file, reloaded when the file changes.
+// Only bcrypt ($2y$) and Apache MD5 ($apr1$) hashes are supported.
+type htpasswdFile struct {
+	mutex   sync.Mutex
+	path    string
+	modTime time.Time
+	users   map[string]string
+}
+
+func loadHtpasswd(path string) (*htpasswdFile, error) {
+	h := &htpasswdFile{path: path}
+	if err := h.reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// reload reads the file again when it was modified since it was last read
+func (h *htpasswdFile) reload() error {
+	info, err := os.Stat(h.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(h.modTime) {
+		return nil
+	}
+
+	f, err := os.Open(h.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pieces := strings.SplitN(line, ":", 2)
+		if len(pieces) != 2 {
+			return fmt.Errorf("%s:%d: expected user:hash", h.path, n)
+		}
+		if !strings.HasPrefix(pieces[1], "$2") && !strings.HasPrefix(pieces[1], "$apr1$") {
+			return fmt.Errorf("%s:%d: unsupported hash for %s, use bcrypt or apr1", h.path, n, pieces[0])
+		}
+		users[pieces[0]] = pieces[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	h.users, h.modTime = users, info.ModTime()
+	return nil
+}
+
+func (h *htpasswdFile) verify(user, password string) bool {
+	h.mutex.Lock()
+	if err := h.reload(); err != nil {
+		// Keep authenticating against the users read last
+		log.Errorf("Unable to reload %s: %s", h.path, err)
+	}
+	hash, ok := h.users[user]
+	h.mutex.Unlock()
+	if !ok {
+		return false
+	}
+
+	if strings.HasPrefix(hash, "$apr1$") {
+		salt := strings.SplitN(strings.TrimPrefix(hash, "$apr1$"), "$", 2)[0]
+		return subtle.ConstantTimeCompare([]byte(apr1(password, salt)), []byte(hash)) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+const apr1Alphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// apr1 returns the Apache MD5 crypt hash of password with salt, as written by htpasswd -m
+func apr1(password, salt string) string {
+	const magic = "$apr1$"
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+	pw := []byte(password)
+
+	alternate := md5.Sum([]byte(password + salt + password))
+
+	h := md5.New()
+	h.Write([]byte(password + magic + salt))
+	for i := len(pw); i > 0; i -= 16 {
+		if i > 16 {
+			h.Write(alternate[:])
+		} else {
+			h.Write(alternate[:i])
+		}
+	}
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 == 1 {
+			h.Write([]byte{0})
+		} else {
+			h.Write(pw[:1])
+		}
+	}
+	final := h.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		h := md5.New()
+		if i&1 == 1 {
+			h.Write(pw)
+		} else {
+			h.Write(final)
+		}
+		if i%3 != 0 {
+			h.Write([]byte(salt))
+		}
+		if i%7 != 0 {
+			h.Write(pw)
+		}
+		if i&1 == 1 {
+			h.Write(final)
+		} else {
+			h.Write(pw)
+		}
+		final = h.Sum(nil)
+	}
+
+	var encoded []byte
+	to64 := func(v uint32, n int) {
+		for ; n > 0; n-- {
+			encoded = append(encoded, apr1Alphabet[v&0x3f])
+			v >>= 6
+		}
+	}
+	for _, i := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		to64(uint32(final[i[0]])<<16|uint32(final[i[1]])<<8|uint32(final[i[2]]), 4)
+	}
+	to64(uint32(final[11]), 2)
+
+	return magic + salt + "$" + string(encoded)
+}
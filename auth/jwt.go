@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+// JWTConfig configures the validation of JWT bearer tokens, against the public keys of a JWKS
+// file or a shared HMAC secret
+type JWTConfig struct {
+	// JWKSFile holds the RSA and EC public keys tokens are signed with, it is reloaded when it changes
+	JWKSFile string
+	// Secret is a shared HMAC secret, used when JWKSFile is empty
+	Secret string
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string
+	Audience string
+	// PrincipalClaim names the claim holding the principal, "sub" by default
+	PrincipalClaim string
+	// GroupsClaim names the claim listing the groups taken as roles, "groups" by default
+	GroupsClaim string
+}
+
+// jwtVerifier validates JWT bearer tokens offline
+type jwtVerifier struct {
+	config JWTConfig
+	jwks   *jwksFile
+	parser *jwt.Parser
+}
+
+func newJWTVerifier(c JWTConfig) (*jwtVerifier, error) {
+	if c.PrincipalClaim == "" {
+		c.PrincipalClaim = "sub"
+	}
+	if c.GroupsClaim == "" {
+		c.GroupsClaim = "groups"
+	}
+
+	options := []jwt.ParserOption{jwt.WithExpirationRequired(), jwt.WithLeeway(time.Minute)}
+	if c.Issuer != "" {
+		options = append(options, jwt.WithIssuer(c.Issuer))
+	}
+	if c.Audience != "" {
+		options = append(options, jwt.WithAudience(c.Audience))
+	}
+
+	v := &jwtVerifier{config: c}
+	switch {
+	case c.JWKSFile != "":
+		jwks, err := loadJWKS(c.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.jwks = jwks
+		options = append(options, jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}))
+	case c.Secret != "":
+		options = append(options, jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}))
+	default:
+		return nil, errors.New("jwt: a JWKS file or a shared secret is required")
+	}
+	v.parser = jwt.NewParser(options...)
+	return v, nil
+}
+
+func (v *jwtVerifier) key(token *jwt.Token) (interface{}, error) {
+	if v.jwks == nil {
+		return []byte(v.config.Secret), nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	return v.jwks.key(kid)
+}
+
+// verify returns the principal and roles carried by a valid token
+func (v *jwtVerifier) verify(token string) (principal string, roles []string, err error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.key); err != nil {
+		return "", nil, err
+	}
+
+	principal, _ = claims[v.config.PrincipalClaim].(string)
+	if principal == "" {
+		return "", nil, fmt.Errorf("jwt: missing %s claim", v.config.PrincipalClaim)
+	}
+
+	switch groups := claims[v.config.GroupsClaim].(type) {
+	case string:
+		roles = strings.Fields(groups)
+	case []interface{}:
+		for _, group := range groups {
+			if role, ok := group.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+	}
+	return principal, roles, nil
+}
+
+// looksLikeJWT tells a compact JWT apart from an API key sent as bearer token
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// jwksFile holds the signing keys of a JSON Web Key Set file, reloaded when the file changes
+type jwksFile struct {
+	mutex   sync.Mutex
+	path    string
+	modTime time.Time
+	keys    map[string]crypto.PublicKey
+}
+
+func loadJWKS(path string) (*jwksFile, error) {
+	j := &jwksFile{path: path}
+	if err := j.reload(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// reload reads the file again when it was modified since it was last read
+func (j *jwksFile) reload() error {
+	info, err := os.Stat(j.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(j.modTime) {
+		return nil
+	}
+
+	dat, err := ioutil.ReadFile(j.path)
+	if err != nil {
+		return err
+	}
+	keys, err := parseJWKS(dat)
+	if err != nil {
+		return fmt.Errorf("%s: %s", j.path, err)
+	}
+
+	j.keys, j.modTime = keys, info.ModTime()
+	return nil
+}
+
+// key returns the key with the given ID, or the only key of the set when kid is empty
+func (j *jwksFile) key(kid string) (crypto.PublicKey, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if err := j.reload(); err != nil {
+		// Keep verifying against the keys read last
+		log.Errorf("Unable to reload %s: %s", j.path, err)
+	}
+
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, nil
+		}
+	}
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("jwt: unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS returns the RSA and EC signing keys of a key set by key ID. Symmetric keys are
+// ignored so a published key can never be used as an HMAC secret.
+func parseJWKS(dat []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(dat, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		switch k.Kty {
+		case "RSA":
+			n, err := decodeBigInt(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: n: %s", k.Kid, err)
+			}
+			e, err := decodeBigInt(k.E)
+			if err != nil || !e.IsInt64() {
+				return nil, fmt.Errorf("key %q: invalid exponent", k.Kid)
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				return nil, fmt.Errorf("key %q: unsupported curve %s", k.Kid, k.Crv)
+			}
+			x, err := decodeBigInt(k.X)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: x: %s", k.Kid, err)
+			}
+			y, err := decodeBigInt(k.Y)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: y: %s", k.Kid, err)
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA or EC signing keys")
+	}
+	return keys, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	dat, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(dat), nil
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cheekybits/is"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeJWKS writes a key set with the public halves of an RSA and an EC key and returns its path
+func writeJWKS(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	dir, err := ioutil.TempDir("", "nagios-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	set := map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa1", "use": "sig", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+		{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+		{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+	}}
+	dat, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "jwks.json")
+	if err := ioutil.WriteFile(path, dat, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJWTAuthentication(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator, err := New(map[string]string{"ci": "s3cret"}, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = authenticator.EnableJWT(JWTConfig{JWKSFile: writeJWKS(t, rsaKey, ecKey), Issuer: "https://sso.example.com", GroupsClaim: "roles"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Configure(authenticator)
+	defer Configure(nil)
+
+	claims := func(extra jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{"sub": "jason", "iss": "https://sso.example.com", "exp": time.Now().Add(time.Hour).Unix(), "roles": []string{"operator", "dba"}}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		code  int
+		body  string
+	}{
+		{name: "rsa", token: sign(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims(nil)), code: 200, body: "jason operator,dba"},
+		{name: "ec", token: sign(t, jwt.SigningMethodES256, "ec1", ecKey, claims(jwt.MapClaims{"roles": "admin"})), code: 200, body: "jason admin"},
+		{name: "api key as bearer", token: "s3cret", code: 200, body: "ci "},
+		{name: "unknown key id", token: sign(t, jwt.SigningMethodRS256, "rsa2", rsaKey, claims(nil)), code: 401},
+		{name: "wrong signature", token: sign(t, jwt.SigningMethodRS256, "rsa1", otherKey, claims(nil)), code: 401},
+		{name: "expired", token: sign(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})), code: 401},
+		{name: "no expiry", token: sign(t, jwt.SigningMethodRS256, "rsa1", rsaKey, jwt.MapClaims{"sub": "jason", "iss": "https://sso.example.com"}), code: 401},
+		{name: "wrong issuer", token: sign(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims(jwt.MapClaims{"iss": "https://evil.example.com"})), code: 401},
+		{name: "no principal", token: sign(t, jwt.SigningMethodRS256, "rsa1", rsaKey, claims(jwt.MapClaims{"sub": ""})), code: 401},
+		{name: "hmac with published key", token: sign(t, jwt.SigningMethodHS256, "hmac", []byte("secret"), claims(nil)), code: 401},
+	}
+
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(Principal(r) + " " + strings.Join(Roles(r), ",")))
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			AuthHandler(echo).ServeHTTP(w, r)
+
+			is.Equal(w.Code, tt.code)
+			if tt.code == 200 {
+				is.Equal(w.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func TestJWTSharedSecret(t *testing.T) {
+	is := is.New(t)
+
+	v, err := newJWTVerifier(JWTConfig{Secret: "shared", Audience: "nagios-api", PrincipalClaim: "preferred_username"})
+	is.NoErr(err)
+
+	token := sign(t, jwt.SigningMethodHS256, "", []byte("shared"), jwt.MapClaims{"preferred_username": "ops", "aud": "nagios-api", "exp": time.Now().Add(time.Hour).Unix()})
+	principal, roles, err := v.verify(token)
+	is.NoErr(err)
+	is.Equal(principal, "ops")
+	is.Equal(len(roles), 0)
+
+	token = sign(t, jwt.SigningMethodHS256, "", []byte("shared"), jwt.MapClaims{"preferred_username": "ops", "aud": "other", "exp": time.Now().Add(time.Hour).Unix()})
+	_, _, err = v.verify(token)
+	is.Err(err)
+
+	_, err = newJWTVerifier(JWTConfig{})
+	is.Err(err)
+}
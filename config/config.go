@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"flag"
 	"io/ioutil"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -13,6 +14,39 @@ type Config struct {
 	ObjectCacheFile string
 	StatusFile      string
 	CommandFile     string
+	NrdpTokens      []string
+	DryRun          bool
+	AuditLog        string
+	// AuditLogMaxSize is the size in MB above which the audit log is rotated, 0 disables rotation
+	AuditLogMaxSize int64
+	AuditLogBackups int
+	// ApiKeys maps principal names to the API key they authenticate with
+	ApiKeys       map[string]string
+	HtpasswdFile  string
+	AnonymousRead bool
+	// JWT bearer tokens are validated against the keys of JwtJwksFile, or else JwtSecret
+	JwtJwksFile       string
+	JwtSecret         string
+	JwtIssuer         string
+	JwtAudience       string
+	JwtPrincipalClaim string
+	JwtGroupsClaim    string
+	// HTTPS is served when TLSCertFile and TLSKeyFile are set. Client certificates are verified
+	// against TLSClientCAFile and their subject authenticates as principal.
+	TLSCertFile          string
+	TLSKeyFile           string
+	TLSClientCAFile      string
+	TLSRequireClientCert bool
+	// status.dat and objects.cache are read again at most every RefreshMinInterval seconds, when
+	// they changed, and checked for changes at least every RefreshMaxInterval seconds
+	RefreshMinInterval int
+	RefreshMaxInterval int
+	// CgiConfigFile is a Nagios cgi.cfg authorizing principals as Nagios contacts
+	CgiConfigFile string
+	// Roles maps role names to the route and command names they allow, only read from the config file
+	Roles map[string][]string
+	// PrincipalRoles assigns roles to principals on top of those in the groups claim of their JWT
+	PrincipalRoles map[string][]string
 }
 
 var (
@@ -22,6 +56,27 @@ var (
 	statusFile      *string
 	commandFile     *string
 	addr            *string
+	nrdpTokens      *string
+	dryRun          *bool
+	auditLog        *string
+	auditLogMaxSize *int64
+	auditLogBackups *int
+	apiKeys         *string
+	htpasswdFile    *string
+	anonymousRead   *bool
+	jwtJwksFile     *string
+	jwtSecret       *string
+	jwtIssuer       *string
+	jwtAudience     *string
+	jwtPrincipal    *string
+	jwtGroups       *string
+	cgiConfigFile   *string
+	tlsCertFile     *string
+	tlsKeyFile      *string
+	tlsClientCA     *string
+	tlsRequireCert  *bool
+	refreshMin      *int
+	refreshMax      *int
 )
 
 func init() {
@@ -30,6 +85,27 @@ func init() {
 	statusFile = flag.String("statusfile", "/usr/local/nagios/var/status.dat", "Nagios status.dat file location")
 	commandFile = flag.String("commandfile", "/usr/local/nagios/var/rw/nagios.cmd", "Nagios command file location")
 	addr = flag.String("addr", ":9090", "The interface and port to run server on")
+	nrdpTokens = flag.String("nrdptokens", "", "Comma separated list of tokens accepted by the NRDP endpoint")
+	dryRun = flag.Bool("dryrun", false, "Validate commands and return the lines that would be written without writing to the command file")
+	auditLog = flag.String("auditlog", "", "JSON-lines file recording every command issued through the API")
+	auditLogMaxSize = flag.Int64("auditlogmaxsize", 100, "Size in MB above which the audit log is rotated")
+	auditLogBackups = flag.Int("auditlogbackups", 5, "Number of rotated audit logs to keep")
+	apiKeys = flag.String("apikeys", "", "Comma separated list of name:key API keys accepted in the X-API-Key header or as bearer token")
+	htpasswdFile = flag.String("htpasswd", "", "htpasswd file (bcrypt or apr1) for HTTP Basic authentication")
+	anonymousRead = flag.Bool("anonymousread", false, "Serve read-only endpoints without credentials")
+	jwtJwksFile = flag.String("jwtjwks", "", "JWKS file with the public keys JWT bearer tokens are signed with")
+	jwtSecret = flag.String("jwtsecret", "", "Shared HMAC secret JWT bearer tokens are signed with, when no JWKS file is given")
+	jwtIssuer = flag.String("jwtissuer", "", "Required iss claim of JWT bearer tokens")
+	jwtAudience = flag.String("jwtaudience", "", "Required aud claim of JWT bearer tokens")
+	jwtPrincipal = flag.String("jwtprincipalclaim", "sub", "JWT claim holding the principal")
+	jwtGroups = flag.String("jwtgroupsclaim", "groups", "JWT claim listing the groups taken as roles")
+	tlsCertFile = flag.String("tlscert", "", "PEM certificate to serve HTTPS with, reloaded when it changes")
+	tlsKeyFile = flag.String("tlskey", "", "PEM private key of the HTTPS certificate")
+	tlsClientCA = flag.String("tlsclientca", "", "PEM CA bundle client certificates are verified against, their subject is taken as principal")
+	tlsRequireCert = flag.Bool("tlsrequireclientcert", false, "Reject TLS connections without a valid client certificate")
+	refreshMin = flag.Int("refreshmin", 1, "Minimum number of seconds between two reads of status.dat or objects.cache")
+	refreshMax = flag.Int("refreshmax", 60, "Maximum number of seconds between two checks of status.dat and objects.cache for changes")
+	cgiConfigFile = flag.String("cgicfg", "", "Nagios cgi.cfg authorizing principals as Nagios contacts, everything is allowed when empty")
 	flag.Parse()
 
 	if *configfile != "" {
@@ -40,7 +116,26 @@ func init() {
 }
 
 func loadConfigFlags() {
-	config = &Config{Addr: *addr, ObjectCacheFile: *objectCacheFile, StatusFile: *statusFile, CommandFile: *commandFile}
+	config = &Config{Addr: *addr, ObjectCacheFile: *objectCacheFile, StatusFile: *statusFile, CommandFile: *commandFile, DryRun: *dryRun,
+		AuditLog: *auditLog, AuditLogMaxSize: *auditLogMaxSize, AuditLogBackups: *auditLogBackups,
+		HtpasswdFile: *htpasswdFile, AnonymousRead: *anonymousRead, CgiConfigFile: *cgiConfigFile,
+		JwtJwksFile: *jwtJwksFile, JwtSecret: *jwtSecret, JwtIssuer: *jwtIssuer, JwtAudience: *jwtAudience,
+		JwtPrincipalClaim: *jwtPrincipal, JwtGroupsClaim: *jwtGroups,
+		TLSCertFile: *tlsCertFile, TLSKeyFile: *tlsKeyFile, TLSClientCAFile: *tlsClientCA, TLSRequireClientCert: *tlsRequireCert,
+		RefreshMinInterval: *refreshMin, RefreshMaxInterval: *refreshMax}
+	if *nrdpTokens != "" {
+		config.NrdpTokens = strings.Split(*nrdpTokens, ",")
+	}
+	if *apiKeys != "" {
+		config.ApiKeys = make(map[string]string)
+		for _, item := range strings.Split(*apiKeys, ",") {
+			pieces := strings.SplitN(item, ":", 2)
+			if len(pieces) != 2 || pieces[0] == "" || pieces[1] == "" {
+				log.Fatal("apikeys: expected name:key, got ", item)
+			}
+			config.ApiKeys[pieces[0]] = pieces[1]
+		}
+	}
 }
 
 func loadConfigFile() {
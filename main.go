@@ -1,16 +1,69 @@
 package main
 
 import (
+	"time"
+
 	"github.com/Sebor/nagios-api/api"
+	"github.com/Sebor/nagios-api/auth"
 	"github.com/Sebor/nagios-api/config"
 	log "github.com/sirupsen/logrus"
 )
 
 func main() {
 	conf := config.GetConfig()
-	api := api.NewAPI(conf.Addr, conf.ObjectCacheFile, conf.CommandFile, conf.StatusFile)
 
-	err := api.Run()
+	authenticator, err := auth.New(conf.ApiKeys, conf.HtpasswdFile, conf.AnonymousRead)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if conf.JwtJwksFile != "" || conf.JwtSecret != "" {
+		err = authenticator.EnableJWT(auth.JWTConfig{
+			JWKSFile:       conf.JwtJwksFile,
+			Secret:         conf.JwtSecret,
+			Issuer:         conf.JwtIssuer,
+			Audience:       conf.JwtAudience,
+			PrincipalClaim: conf.JwtPrincipalClaim,
+			GroupsClaim:    conf.JwtGroupsClaim,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if conf.TLSClientCAFile != "" {
+		authenticator.EnableClientCertificates()
+	}
+	if !authenticator.Enabled() {
+		log.Warn("No API keys, JWT keys, htpasswd file or client CA configured, authentication is disabled")
+	}
+	auth.Configure(authenticator)
+
+	api := api.NewAPI(conf.Addr, conf.ObjectCacheFile, conf.CommandFile, conf.StatusFile, conf.NrdpTokens, conf.DryRun)
+
+	api.SetRefreshIntervals(time.Duration(conf.RefreshMinInterval)*time.Second, time.Duration(conf.RefreshMaxInterval)*time.Second)
+
+	if conf.AuditLog != "" {
+		if err := api.EnableAuditLog(conf.AuditLog, conf.AuditLogMaxSize*1024*1024, conf.AuditLogBackups); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if conf.TLSCertFile != "" || conf.TLSKeyFile != "" {
+		if err := api.EnableTLS(conf.TLSCertFile, conf.TLSKeyFile, conf.TLSClientCAFile, conf.TLSRequireClientCert); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if len(conf.Roles) > 0 {
+		api.EnableRoles(conf.Roles, conf.PrincipalRoles)
+	}
+
+	if conf.CgiConfigFile != "" {
+		if err := api.EnableAuthorization(conf.CgiConfigFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = api.Run()
 	if err != nil {
 		log.Fatal(err)
 	}